@@ -0,0 +1,106 @@
+package squiggle
+
+// Returns a deep copy of the query.  Changes made to the copy, including
+// changes to its criteria, don't affect the original query.
+//
+// 	base := squiggle.Select().AddFrom("users").Where("is_deleted = false")
+// 	admins := base.Clone().AndWhere("is_admin = true")
+// 	// base is still SELECT * FROM users WHERE is_deleted = false
+func (q *Query) Clone() *Query {
+	c := *q
+	c.from = nil
+	for _, from := range q.from {
+		if from.Subquery != nil {
+			from.Subquery = from.Subquery.Clone()
+		}
+		c.from = append(c.from, from)
+	}
+	c.fields = append([]Field(nil), q.fields...)
+	c.groupings = append([]Grouping(nil), q.groupings...)
+	c.orderings = append([]Ordering(nil), q.orderings...)
+	c.insertColumns = append([]string(nil), q.insertColumns...)
+
+	c.joins = nil
+	for _, join := range q.joins {
+		join.On = join.On.clone()
+		c.joins = append(c.joins, join)
+	}
+
+	c.insertRows = nil
+	for _, row := range q.insertRows {
+		c.insertRows = append(c.insertRows, append([]interface{}(nil), row...))
+	}
+
+	c.where = q.where.clone()
+	c.having = q.having.clone()
+
+	c.allowedIdentifiers = append([]string(nil), q.allowedIdentifiers...)
+	c.middleware = append([]Middleware(nil), q.middleware...)
+	c.skipMiddleware = append([]string(nil), q.skipMiddleware...)
+
+	c.tags = nil
+	if q.tags != nil {
+		c.tags = map[string]string{}
+		for k, v := range q.tags {
+			c.tags[k] = v
+		}
+	}
+
+	c.values = nil
+	if q.values != nil {
+		c.values = map[interface{}]interface{}{}
+		for k, v := range q.values {
+			c.values[k] = v
+		}
+	}
+
+	return &c
+}
+
+// Returns an immutable copy of the query.  Every method called on an
+// immutable query leaves it unchanged and returns a modified copy instead, so
+// a base query can safely be shared.
+//
+// 	base := squiggle.Select().AddFrom("users").Immutable()
+// 	page := base.Limit(10)
+// 	// base is still SELECT * FROM users
+func (q *Query) Immutable() *Query {
+	c := q.Clone()
+	c.immutable = true
+	return c
+}
+
+// Returns a copy of the query with the default behavior of methods modifying
+// the query in place
+func (q *Query) Mutable() *Query {
+	c := q.Clone()
+	c.immutable = false
+	return c
+}
+
+// returns the query a method should modify, a copy when the query is
+// immutable
+func (q *Query) builder() *Query {
+	if q.immutable {
+		return q.Clone()
+	}
+	return q
+}
+
+// returns a deep copy of the criteria
+func (c Criteria) clone() Criteria {
+	if c.expressions == nil {
+		return c
+	}
+
+	expressions := make([]interface{}, len(c.expressions))
+	for i, expression := range c.expressions {
+		if criteria, ok := expression.(Criteria); ok {
+			expression = criteria.clone()
+		}
+		expressions[i] = expression
+	}
+	c.expressions = expressions
+
+	return c
+}
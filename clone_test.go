@@ -0,0 +1,54 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_Clone(t *testing.T) {
+	q1 := Select().
+		AddFrom("users").
+		AddField("id").
+		AddJoin(Join{Type: "inner", Table: "roles", On: And("roles.id = users.role_id")}).
+		Where(And("a = 1", Or("b = 2", "c = 3")))
+	expected := q1.String()
+
+	q2 := q1.Clone().
+		AddFrom("foo").
+		AddField("username").
+		AndWhere("d = 4").
+		Limit(10)
+	q2.joins[0].On.expressions[0] = "roles.id = 1"
+	q2.where.expressions[1].(Criteria).expressions[0] = "b = 5"
+
+	if str := q1.String(); str != expected {
+		t.Errorf("Clone() changes affected the original query `%s` expected `%s`", str, expected)
+	}
+
+	q3 := Select().AddFrom("users").Tag("route", "/users").WithValue("tenant", 1).Use(Middleware{Name: "noop"})
+	q4 := q3.Clone()
+	q4.tags["route"] = "/admin"
+	q4.values["tenant"] = 2
+	q4.middleware[0].Name = "other"
+	if q3.tags["route"] != "/users" || q3.Value("tenant") != 1 || q3.middleware[0].Name != "noop" {
+		t.Errorf("Clone() shared tags, values or middleware with the original query")
+	}
+}
+
+func Test_Immutable(t *testing.T) {
+	base := Select().AddFrom("users").Immutable()
+
+	page := base.Add(Field{Name: "id"}, Ordering{Field: "id"}).Where("a = 1").Limit(10)
+	if str := base.String(); str != "SELECT * FROM users" {
+		t.Errorf("immutable query was modified `%s`", str)
+	}
+	expected := "SELECT id FROM users WHERE a = 1 ORDER BY id ASC LIMIT 10"
+	if str := page.String(); str != expected {
+		t.Errorf("String() returned `%s` expected `%s`", str, expected)
+	}
+
+	q := base.Mutable()
+	q.Limit(5)
+	if q.limit != 5 {
+		t.Error("Mutable() query was not modified in place")
+	}
+}
@@ -0,0 +1,114 @@
+package squiggle
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Where the comment holding the tags of a query is placed
+type CommentPosition int
+
+const (
+	CommentEnd CommentPosition = iota
+	CommentStart
+)
+
+// Tags a query with a key and value, for example the service or route the
+// query comes from.  Tags are rendered in a comment in the style of
+// sqlcommenter so the database can attribute queries: keys and values are
+// URL encoded, values are quoted and tags are sorted by key.  Queries are
+// also tagged with the tags of the context they're executed with by a
+// Runner, see ContextWithTags().
+//
+// 	squiggle.Select().AddFrom("users").Tag("app", "billing").Tag("route", "/users/{id}")
+// 	// => SELECT * FROM users /*app='billing',route='%2Fusers%2F%7Bid%7D'*/
+func (q *Query) Tag(key, value string) *Query {
+	q = q.builder()
+	tags := map[string]string{}
+	for k, v := range q.tags {
+		tags[k] = v
+	}
+	tags[key] = value
+	q.tags = tags
+
+	return q
+}
+
+// Returns the tags of a query
+func (q *Query) Tags() map[string]string {
+	tags := map[string]string{}
+	for k, v := range q.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// Sets where the comment holding the tags of a query is placed.  The
+// default is the end of the query, some tools only look at the start.
+func (q *Query) CommentAt(position CommentPosition) *Query {
+	q = q.builder()
+	q.commentPosition = position
+
+	return q
+}
+
+type tagsKey struct{}
+
+// Returns a context carrying tags that a Runner adds to every query
+// executed with it.  Tags of the query take precedence, tags already in the
+// context are kept unless they're replaced.
+//
+// 	ctx = squiggle.ContextWithTags(ctx, map[string]string{"route": r.URL.Path})
+func ContextWithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := map[string]string{}
+	for k, v := range TagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, tagsKey{}, merged)
+}
+
+// Returns the tags a context carries
+func TagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey{}).(map[string]string)
+	return tags
+}
+
+// returns the query tagged with tags it doesn't have yet
+func (q *Query) withDefaultTags(tags map[string]string) *Query {
+	for k, v := range tags {
+		if _, ok := q.tags[k]; !ok {
+			q = q.Immutable().Tag(k, v)
+		}
+	}
+	return q
+}
+
+// returns the comment holding the tags of the query, nothing when it has no
+// tags
+func (q *Query) comment() string {
+	if len(q.tags) == 0 {
+		return ""
+	}
+
+	var keys []string
+	for key := range q.tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, commentEscape(key)+"='"+commentEscape(q.tags[key])+"'")
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+// URL encodes a key or value of a tag, this also takes care of quotes and
+// of */ ending the comment
+func commentEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
@@ -0,0 +1,57 @@
+package squiggle
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func Test_QueryTag(t *testing.T) {
+	q1 := Select().AddFrom("users").Where(Eq("id", 1)).Tag("route", "/users/{id}").Tag("app", "billing")
+
+	tests := []struct {
+		q        *Query
+		expected string
+	}{
+		{q1, "SELECT * FROM users WHERE id = ? /*app='billing',route='%2Fusers%2F%7Bid%7D'*/"},
+		{q1.Clone().CommentAt(CommentStart), "/*app='billing',route='%2Fusers%2F%7Bid%7D'*/ SELECT * FROM users WHERE id = ?"},
+		{Select().AddFrom("users").Tag("note", "it's */ DROP TABLE users; /*"), "SELECT * FROM users /*note='it%27s%20%2A%2F%20DROP%20TABLE%20users%3B%20%2F%2A'*/"},
+		{Select().AddFrom("users"), "SELECT * FROM users"},
+	}
+	for _, test := range tests {
+		if str := test.q.String(); str != test.expected {
+			t.Errorf("String() returned `%s` expected `%s`", str, test.expected)
+		}
+	}
+
+	expected := "SELECT\n  *\nFROM\n  users\nWHERE\n  id = ?\n/*app='billing',route='%2Fusers%2F%7Bid%7D'*/"
+	if str := q1.Format(FormatOptions{}); str != expected {
+		t.Errorf("Format() returned `%s` expected `%s`", str, expected)
+	}
+	if sql, _ := q1.Fingerprint(); sql != "SELECT * FROM users WHERE id = ?" {
+		t.Errorf("Fingerprint() returned `%s` with tags", sql)
+	}
+	if !reflect.DeepEqual(q1.Tags(), map[string]string{"app": "billing", "route": "/users/{id}"}) {
+		t.Errorf("Tags() returned %v", q1.Tags())
+	}
+}
+
+func Test_ContextWithTags(t *testing.T) {
+	db, _ := sql.Open("squiggle-fake", "")
+	defer db.Close()
+	fake.queries = nil
+
+	ctx := ContextWithTags(context.Background(), map[string]string{"app": "billing", "route": "/a"})
+	ctx = ContextWithTags(ctx, map[string]string{"route": "/b"})
+	q1 := Select().AddFrom("users").Tag("app", "reports")
+
+	NewRunner(db).Query(ctx, q1)
+	expected := []string{"SELECT * FROM users /*app='reports',route='%2Fb'*/"}
+	if !reflect.DeepEqual(fake.queries, expected) {
+		t.Errorf("database received %v expected %v", fake.queries, expected)
+	}
+	if len(q1.Tags()) != 1 {
+		t.Error("the runner modified the tags of the query")
+	}
+}
@@ -0,0 +1,41 @@
+package squiggle
+
+// Derives a query counting the rows the query would return without its
+// limit and offset, for example to get the total number of rows for
+// pagination.  Orderings, limit and offset are dropped.  When the query uses
+// GROUP BY, HAVING or DISTINCT it's wrapped in a subquery so the count is of
+// the groups or distinct rows, the outer query keeps the settings of the
+// query.  The original query is not modified.
+//
+// 	q := squiggle.Select().AddFrom("users").Where("is_admin = $1").AddOrdering("id").Limit(10)
+// 	q.CountQuery()
+// 	// => SELECT COUNT(*) FROM users WHERE is_admin = $1
+//
+// 	q = squiggle.Select().AddField("country").AddFrom("users").AddGrouping("country")
+// 	q.CountQuery()
+// 	// => SELECT COUNT(*) FROM (SELECT country FROM users GROUP BY country) count_query
+func (q *Query) CountQuery() *Query {
+	inner := q.Mutable().ClearOrderings().ResetLimit().ResetOffset()
+
+	count := Field{Expression: "COUNT(*)"}
+	if len(inner.groupings) == 0 && len(inner.having.expressions) == 0 && !inner.distinct {
+		inner.fields = []Field{count}
+		inner.immutable = q.immutable
+		return inner
+	}
+
+	outer := Select().AddField(count).AddFrom(From{Subquery: inner, Alias: "count_query"})
+	outer.dialect = inner.Dialect()
+	outer.identifierLeftQuote = q.identifierLeftQuote
+	outer.identifierRightQuote = q.identifierRightQuote
+	outer.placeholder = q.placeholder
+	outer.formatter = q.formatter
+	outer.strictIdentifiers = q.strictIdentifiers
+	outer.allowedIdentifiers = inner.allowedIdentifiers
+	outer.strictValidation = q.strictValidation
+	outer.tags = inner.tags
+	outer.commentPosition = q.commentPosition
+	outer.immutable = q.immutable
+
+	return outer
+}
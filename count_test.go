@@ -0,0 +1,46 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_CountQuery(t *testing.T) {
+	q1 := Select().AddField("id").AddFrom("users").Where(Eq("is_admin", true)).AddOrdering("id").Limit(10).Offset(20)
+
+	sql, args := q1.CountQuery().ToSQL()
+	expected := "SELECT COUNT(*) FROM users WHERE is_admin = ?"
+	if sql != expected || len(args) != 1 {
+		t.Errorf("CountQuery() returned `%s` expected `%s`", sql, expected)
+	}
+	if str := q1.String(); str != "SELECT id FROM users WHERE is_admin = ? ORDER BY id ASC LIMIT 10 OFFSET 20" {
+		t.Errorf("CountQuery() modified the original query `%s`", str)
+	}
+
+	q2 := Select().
+		SetIdentifierQuotes(`"`).
+		AddField("country").
+		AddFrom("users").
+		AddGrouping("country").
+		Having("COUNT(*) > 1").
+		AddOrdering("country")
+	expected = `SELECT COUNT(*) FROM (SELECT "country" FROM "users" GROUP BY "country" HAVING COUNT(*) > 1) "count_query"`
+	if str := q2.CountQuery().String(); str != expected {
+		t.Errorf("CountQuery() returned `%s` expected `%s`", str, expected)
+	}
+
+	q3 := Select().Distinct().AddField("country").AddFrom("users")
+	expected = "SELECT COUNT(*) FROM (SELECT DISTINCT country FROM users) count_query"
+	if str := q3.CountQuery().String(); str != expected {
+		t.Errorf("CountQuery() returned `%s` expected `%s`", str, expected)
+	}
+
+	q4 := Select().SetDialect(PostgreSQL).Distinct().AddField("country").AddFrom("users").Where(Eq("active", true)).Tag("route", "/countries")
+	sql, _ = q4.CountQuery().ToSQL()
+	expected = `SELECT COUNT(*) FROM (SELECT DISTINCT "country" FROM "users" WHERE "active" = $1) "count_query" /*route='%2Fcountries'*/`
+	if sql != expected {
+		t.Errorf("CountQuery() returned `%s` expected `%s`", sql, expected)
+	}
+	if q4.CountQuery().Dialect().Name != "postgres" {
+		t.Errorf("CountQuery() returned a query without the dialect of the query")
+	}
+}
@@ -2,7 +2,6 @@ package squiggle
 
 import (
 	"fmt"
-	"strings"
 )
 
 type Criteria struct {
@@ -12,27 +11,54 @@ type Criteria struct {
 
 // returns a criteria as an SQL string
 func (c Criteria) String() string {
-	var parts []string
+	sql, _ := c.ToSQL()
+	return sql
+}
+
+// returns a criteria as an SQL string along with the values bound to the
+// placeholders of its predicates
+//
+// 	squiggle.And("a=1", squiggle.Eq("b", 2)).ToSQL()
+// 	// => "a=1 AND b = ?", []interface{}{2}
+func (c Criteria) ToSQL() (string, []interface{}) {
+	w := &sqlWriter{}
+	c.writeSQL(new(Query), w, 0)
+	return w.String(), w.args
+}
 
-	for _, expression := range c.expressions {
+// writes a criteria using the identifier quotes, placeholders and formatting
+// of the query.  level is the indentation level of the criteria when
+// formatting.
+func (c Criteria) writeSQL(q *Query, w *sqlWriter, level int) {
+	separator := q.line(level) + q.keyword("OR") + " "
+	if c.and {
+		separator = q.line(level) + q.keyword("AND") + " "
+	}
+
+	for i, expression := range c.expressions {
+		if i > 0 {
+			w.write(separator)
+		}
 		switch expression.(type) {
 		default:
 			panic(fmt.Sprintf("unexpected type %T in criteria", expression))
 		case string:
-			parts = append(parts, expression.(string))
+			w.write(expression.(string))
 		case Criteria:
-			parts = append(parts, `(`+(expression.(Criteria)).String()+`)`)
+			w.write(`(`)
+			w.write(q.breakLine(level + 1))
+			expression.(Criteria).writeSQL(q, w, level+1)
+			w.write(q.breakLine(level))
+			w.write(`)`)
+		case Expression:
+			expression.(Expression).expressionSQL(q, w)
 		}
 	}
-
-	if c.and {
-		return strings.Join(parts, " AND ")
-	}
-	return strings.Join(parts, " OR ")
 }
 
 // Creates a criteria with the logic of AND.  Accepts any number of arguments
-// of type string or squiggle.Criteria.
+// of type string, squiggle.Criteria or squiggle.Expression such as
+// squiggle.Predicate.
 //
 // 	squiggle.And("a=1", squiggle.Or("b=2", "c=3", squiggle.And("d=4", "e=5")))
 // 	// => a=1 AND (b=2 OR c=3 OR (d=4 AND e=5))
@@ -44,7 +70,7 @@ func And(args ...interface{}) Criteria {
 			panic(fmt.Sprintf("unexpected type %T used in And()", arg))
 		case string:
 			c.expressions = append(c.expressions, arg)
-		case Criteria:
+		case Expression:
 			c.expressions = append(c.expressions, arg)
 		}
 	}
@@ -62,10 +88,25 @@ func Or(args ...interface{}) Criteria {
 			panic(fmt.Sprintf("unexpected type %T used in Or()", arg))
 		case string:
 			c.expressions = append(c.expressions, arg)
-		case Criteria:
+		case Expression:
 			c.expressions = append(c.expressions, arg)
 		}
 	}
 
 	return c
 }
+
+// converts the argument of a criteria method such as Where() to a Criteria.
+// Strings and expressions become a criteria with a single expression.
+func toCriteria(c interface{}, method string) Criteria {
+	switch c.(type) {
+	default:
+		panic(fmt.Sprintf("unexpected type %T used in %s()", c, method))
+	case string:
+		return And(c.(string))
+	case Criteria:
+		return c.(Criteria)
+	case Expression:
+		return And(c.(Expression))
+	}
+}
@@ -0,0 +1,70 @@
+package squiggle
+
+import (
+	"fmt"
+)
+
+// Create a new DELETE query.  The table may be a string or a squiggle.From.
+// Deleting from a table registered with a soft delete column sets the column
+// instead, see HardDelete().
+//
+// 	squiggle.Delete("users").Where(squiggle.Eq("id", 1))
+// 	// => DELETE FROM users WHERE id = ?
+func Delete(table interface{}) *Query {
+	q := new(Query)
+	q.queryType = "DELETE"
+
+	switch table.(type) {
+	default:
+		panic(fmt.Sprintf("unexpected type %T used in Delete()", table))
+	case string:
+		q.from = append(q.from, From{Table: table.(string)})
+	case From:
+		q.from = append(q.from, table.(From))
+	}
+
+	return q
+}
+
+// Makes a DELETE query delete rows of a table with a soft delete column
+// instead of setting the column
+func (q *Query) HardDelete() *Query {
+	q = q.builder()
+	q.hardDelete = true
+
+	return q
+}
+
+// writes a DELETE query.  Soft deletes are rendered as an UPDATE of the rows
+// that haven't been deleted yet.
+func (q *Query) deleteSQL(w *sqlWriter) {
+	if len(q.from) == 0 {
+		w.write(q.keyword("DELETE FROM"))
+		return
+	}
+
+	from := q.from[0]
+	table, ok := lookupTable(from.Table)
+	if !ok || table.SoftDelete == "" || q.hardDelete {
+		w.write(q.keyword("DELETE FROM"))
+		w.write(" ")
+		q.writeQualified(w, from.Schema, "", from.Table)
+		q.writeCriteria(w, "WHERE", q.where)
+		return
+	}
+
+	value := table.SoftDeleteValue
+	if value == nil {
+		value = Raw("CURRENT_TIMESTAMP")
+	}
+	w.write(q.keyword("UPDATE"))
+	w.write(" ")
+	q.writeQualified(w, from.Schema, "", from.Table)
+	w.write(q.line(0))
+	w.write(q.keyword("SET"))
+	w.write(q.listSeparator(0))
+	q.writeIdentifier(w, table.SoftDelete)
+	w.write(" = ")
+	value.expressionSQL(q, w)
+	q.writeCriteria(w, "WHERE", andCriteria(q.where, IsNull(table.SoftDelete)))
+}
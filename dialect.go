@@ -0,0 +1,81 @@
+package squiggle
+
+// A Dialect describes the flavor of SQL spoken by a database: how
+// identifiers are quoted and what placeholders look like.
+type Dialect struct {
+	Name                 string
+	IdentifierLeftQuote  string
+	IdentifierRightQuote string
+	Placeholder          string
+	// translations of upper case function names used by squiggle.Func
+	Functions map[string]string
+}
+
+var (
+	MySQL = Dialect{
+		Name:                 "mysql",
+		IdentifierLeftQuote:  "`",
+		IdentifierRightQuote: "`",
+		Placeholder:          "?",
+		Functions:            map[string]string{"LEN": "CHAR_LENGTH", "GETDATE": "NOW"},
+	}
+	PostgreSQL = Dialect{
+		Name:                 "postgres",
+		IdentifierLeftQuote:  `"`,
+		IdentifierRightQuote: `"`,
+		Placeholder:          "$",
+		Functions:            map[string]string{"LEN": "LENGTH", "IFNULL": "COALESCE", "ISNULL": "COALESCE", "GETDATE": "NOW"},
+	}
+	SQLite = Dialect{
+		Name:                 "sqlite",
+		IdentifierLeftQuote:  `"`,
+		IdentifierRightQuote: `"`,
+		Placeholder:          "?",
+		Functions:            map[string]string{"LEN": "LENGTH", "SUBSTRING": "SUBSTR", "ISNULL": "IFNULL"},
+	}
+	SQLServer = Dialect{
+		Name:                 "sqlserver",
+		IdentifierLeftQuote:  "[",
+		IdentifierRightQuote: "]",
+		Placeholder:          "@p",
+		Functions:            map[string]string{"LENGTH": "LEN", "CHAR_LENGTH": "LEN", "SUBSTR": "SUBSTRING", "IFNULL": "ISNULL", "NOW": "GETDATE"},
+	}
+)
+
+// Sets the dialect of a query.  This sets the identifier quotes and the
+// placeholder of the query to those of the dialect, they can still be changed
+// afterwards with SetIdentifierQuotes() and SetPlaceholder().  The query
+// keeps its own copy of the function translations, changing those of the
+// dialect afterwards doesn't change the query.
+//
+// 	squiggle.Select().SetDialect(squiggle.PostgreSQL).AddFrom("users").Where(squiggle.Eq("id", 1))
+// 	// => SELECT * FROM "users" WHERE "id" = $1
+func (q *Query) SetDialect(dialect Dialect) *Query {
+	q = q.builder()
+	q.dialect = dialect
+	q.dialect.Functions = copyFunctions(dialect.Functions)
+	q.identifierLeftQuote = dialect.IdentifierLeftQuote
+	q.identifierRightQuote = dialect.IdentifierRightQuote
+	q.placeholder = dialect.Placeholder
+
+	return q
+}
+
+// Returns the dialect of a query
+func (q *Query) Dialect() Dialect {
+	dialect := q.dialect
+	dialect.Functions = copyFunctions(dialect.Functions)
+	return dialect
+}
+
+// returns a copy of the function translations of a dialect
+func copyFunctions(functions map[string]string) map[string]string {
+	if functions == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(functions))
+	for name, translated := range functions {
+		copied[name] = translated
+	}
+	return copied
+}
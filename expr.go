@@ -0,0 +1,322 @@
+package squiggle
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An Expression is a node of an SQL expression tree.  Expressions can be
+// used as criteria, inside And() and Or(), and as Field.Expr.  Unlike raw
+// strings squiggle quotes the identifiers of expressions, binds their
+// parameters and translates their function names for the dialect of the
+// query.  Predicates and criteria are expressions too.
+//
+// 	squiggle.Select().
+// 		AddField(squiggle.Field{Expr: squiggle.Fn("LENGTH", squiggle.Col("u.name")), Alias: "len"}).
+// 		AddFrom(squiggle.From{Table: "users", Alias: "u"}).
+// 		Where(squiggle.Op(squiggle.Col("u.age"), ">", squiggle.Arg(30))).
+// 		SetDialect(squiggle.SQLServer)
+// 	// => SELECT LEN([u].[name]) AS [len] FROM [users] [u] WHERE [u].[age] > @p1
+type Expression interface {
+	expressionSQL(q *Query, w *sqlWriter)
+}
+
+// A reference to a column
+type Column struct {
+	Schema string
+	Table  string
+	Name   string
+}
+
+// A literal value rendered into the SQL.  Strings are quoted and escaped,
+// nil is NULL.  Use Param for values that come from users.
+type Literal struct {
+	Value interface{}
+}
+
+// A value bound to a placeholder
+type Param struct {
+	Value interface{}
+}
+
+// A raw string of SQL inside an expression tree
+type Raw string
+
+// A function call.  The name is translated for the dialect of the query.
+type Func struct {
+	Name string
+	Args []Expression
+}
+
+// A binary operator such as =, +, LIKE or AND.  Operands that are binary or
+// unary operators themselves are wrapped in parentheses.
+type Binary struct {
+	Left  Expression
+	Op    string
+	Right Expression
+}
+
+// A prefix unary operator such as NOT or -
+type Unary struct {
+	Op      string
+	Operand Expression
+}
+
+// A CASE expression.  Operand is optional, without it each When.Cond is a
+// condition.
+type Case struct {
+	Operand Expression
+	Whens   []When
+	Else    Expression
+}
+
+// A WHEN ... THEN ... branch of a CASE expression
+type When struct {
+	Cond   Expression
+	Result Expression
+}
+
+// A CAST(... AS type) expression
+type Cast struct {
+	Expr Expression
+	Type string
+}
+
+// A parenthesized list of expressions, for example the right side of IN
+type List []Expression
+
+// SQL text with expressions such as Param inside of it, rendered one after
+// the other.  Parse() turns conditions with placeholders into fragments so
+// their values are bound like any other.
+//
+// 	squiggle.Fragment{squiggle.Raw("id = "), squiggle.Arg(1)}
+// 	// => id = ?
+type Fragment []Expression
+
+// Creates a column reference from a possibly dotted name
+//
+// 	squiggle.Col("db.users.id") // => Column{Schema: "db", Table: "users", Name: "id"}
+func Col(name string) Column {
+	parts := strings.Split(name, ".")
+	c := Column{Name: parts[len(parts)-1]}
+	if len(parts) > 1 {
+		c.Table = parts[len(parts)-2]
+	}
+	if len(parts) > 2 {
+		c.Schema = strings.Join(parts[:len(parts)-2], ".")
+	}
+	return c
+}
+
+// Creates a literal value
+func Lit(value interface{}) Literal {
+	return Literal{Value: value}
+}
+
+// Creates a value bound to a placeholder
+func Arg(value interface{}) Param {
+	return Param{Value: value}
+}
+
+// Creates a function call
+func Fn(name string, args ...Expression) Func {
+	return Func{Name: name, Args: args}
+}
+
+// Creates a binary operator expression
+func Op(left Expression, op string, right Expression) Binary {
+	return Binary{Left: left, Op: op, Right: right}
+}
+
+// Creates a NOT expression.  Accepts a string, squiggle.Criteria or
+// squiggle.Expression.
+//
+// 	squiggle.Not(squiggle.Or("a = 1", "b = 2"))
+// 	// => NOT (a = 1 OR b = 2)
+func Not(c interface{}) Unary {
+	switch c.(type) {
+	default:
+		panic(fmt.Sprintf("unexpected type %T used in Not()", c))
+	case string:
+		return Unary{Op: "NOT", Operand: And(c.(string))}
+	case Expression:
+		return Unary{Op: "NOT", Operand: c.(Expression)}
+	}
+}
+
+func (c Column) expressionSQL(q *Query, w *sqlWriter) {
+	q.writeQualified(w, c.Schema, c.Table, c.Name)
+}
+
+func (l Literal) expressionSQL(q *Query, w *sqlWriter) {
+	switch value := l.Value.(type) {
+	case nil:
+		w.write(q.keyword("NULL"))
+		return
+	case []byte:
+		w.write(q.quoteString(string(value)))
+		return
+	case time.Time:
+		w.write(q.quoteString(value.Format("2006-01-02 15:04:05.999999999")))
+		return
+	}
+
+	// by kind so named types such as type Status string are literals too
+	value := reflect.ValueOf(l.Value)
+	switch value.Kind() {
+	default:
+		panic(fmt.Sprintf("unexpected type %T used in Literal", l.Value))
+	case reflect.Bool:
+		if value.Bool() {
+			w.write(q.keyword("TRUE"))
+		} else {
+			w.write(q.keyword("FALSE"))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.buf = strconv.AppendInt(w.buf, value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		w.buf = strconv.AppendUint(w.buf, value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		w.buf = strconv.AppendFloat(w.buf, value.Float(), 'g', -1, value.Type().Bits())
+	case reflect.String:
+		w.write(q.quoteString(value.String()))
+	}
+}
+
+// returns a string literal, MySQL also treats backslashes as escapes
+func (q *Query) quoteString(s string) string {
+	s = strings.Replace(s, "'", "''", -1)
+	if q.dialect.Name == MySQL.Name {
+		s = strings.Replace(s, `\`, `\\`, -1)
+	}
+	return "'" + s + "'"
+}
+
+func (p Param) expressionSQL(q *Query, w *sqlWriter) {
+	q.bind(w, p.Value)
+}
+
+func (r Raw) expressionSQL(q *Query, w *sqlWriter) {
+	w.write(string(r))
+}
+
+func (f Func) expressionSQL(q *Query, w *sqlWriter) {
+	name := f.Name
+	if translated, ok := q.dialect.Functions[strings.ToUpper(name)]; ok {
+		name = translated
+	}
+
+	w.write(name)
+	w.write("(")
+	for i, arg := range f.Args {
+		if i > 0 {
+			w.write(", ")
+		}
+		arg.expressionSQL(q, w)
+	}
+	w.write(")")
+}
+
+func (b Binary) expressionSQL(q *Query, w *sqlWriter) {
+	writeOperand(q, w, b.Left)
+	w.write(" ")
+	w.write(q.keyword(strings.ToUpper(b.Op)))
+	w.write(" ")
+	writeOperand(q, w, b.Right)
+}
+
+func (u Unary) expressionSQL(q *Query, w *sqlWriter) {
+	op := strings.ToUpper(u.Op)
+	w.write(q.keyword(op))
+	// word operators such as NOT and EXISTS are followed by a space
+	if op != "" && op[len(op)-1] >= 'A' && op[len(op)-1] <= 'Z' {
+		w.write(" ")
+	}
+	start := len(w.buf)
+	writeOperand(q, w, u.Operand)
+	// an operand such as -3 after - would start a -- comment
+	if strings.HasSuffix(op, "-") && len(w.buf) > start && w.buf[start] == '-' {
+		w.buf = append(w.buf[:start], append([]byte(" "), w.buf[start:]...)...)
+	}
+}
+
+// writes an operand of an operator, wrapped in parentheses when it's an
+// operator itself
+func writeOperand(q *Query, w *sqlWriter, e Expression) {
+	switch e.(type) {
+	case Binary, Unary:
+		w.write("(")
+		e.expressionSQL(q, w)
+		w.write(")")
+	default:
+		e.expressionSQL(q, w)
+	}
+}
+
+func (c Case) expressionSQL(q *Query, w *sqlWriter) {
+	w.write(q.keyword("CASE"))
+	if c.Operand != nil {
+		w.write(" ")
+		c.Operand.expressionSQL(q, w)
+	}
+	for _, when := range c.Whens {
+		w.write(" ")
+		w.write(q.keyword("WHEN"))
+		w.write(" ")
+		when.Cond.expressionSQL(q, w)
+		w.write(" ")
+		w.write(q.keyword("THEN"))
+		w.write(" ")
+		when.Result.expressionSQL(q, w)
+	}
+	if c.Else != nil {
+		w.write(" ")
+		w.write(q.keyword("ELSE"))
+		w.write(" ")
+		c.Else.expressionSQL(q, w)
+	}
+	w.write(" ")
+	w.write(q.keyword("END"))
+}
+
+func (c Cast) expressionSQL(q *Query, w *sqlWriter) {
+	w.write(q.keyword("CAST"))
+	w.write("(")
+	c.Expr.expressionSQL(q, w)
+	w.write(" ")
+	w.write(q.keyword("AS"))
+	w.write(" ")
+	w.write(c.Type)
+	w.write(")")
+}
+
+func (l List) expressionSQL(q *Query, w *sqlWriter) {
+	w.write("(")
+	for i, e := range l {
+		if i > 0 {
+			w.write(", ")
+		}
+		e.expressionSQL(q, w)
+	}
+	w.write(")")
+}
+
+func (f Fragment) expressionSQL(q *Query, w *sqlWriter) {
+	for _, e := range f {
+		e.expressionSQL(q, w)
+	}
+}
+
+func (p Predicate) expressionSQL(q *Query, w *sqlWriter) {
+	p.writeSQL(q, w)
+}
+
+// criteria inside of an expression are wrapped in parentheses
+func (c Criteria) expressionSQL(q *Query, w *sqlWriter) {
+	w.write("(")
+	c.writeSQL(q, w, 0)
+	w.write(")")
+}
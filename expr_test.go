@@ -0,0 +1,75 @@
+package squiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Col(t *testing.T) {
+	if c := Col("db.users.id"); c.Schema != "db" || c.Table != "users" || c.Name != "id" {
+		t.Errorf("Col() returned unexpected column %+v", c)
+	}
+}
+
+func Test_ExpressionSQL(t *testing.T) {
+	q1 := Select().
+		SetDialect(SQLServer).
+		AddField(Field{Expr: Fn("LENGTH", Col("u.name")), Alias: "len"}).
+		AddField(Field{Expr: Case{
+			Whens: []When{{Cond: Op(Col("age"), "<", Lit(18)), Result: Lit("minor")}},
+			Else:  Lit("adult's"),
+		}}).
+		AddField(Field{Expr: Cast{Expr: Arg("5"), Type: "INT"}}).
+		AddFrom(From{Table: "users", Alias: "u"}).
+		Where(And(
+			Op(Col("u.age"), ">", Op(Arg(30), "+", Lit(1))),
+			Not(Or("a = 1", Op(Col("role"), "in", List{Lit("admin"), Lit(nil)}))),
+			Unary{Op: "-", Operand: Col("score")},
+		))
+
+	sql, args := q1.ToSQL()
+	expected := "SELECT LEN([u].[name]) AS [len], CASE WHEN [age] < 18 THEN 'minor' ELSE 'adult''s' END, CAST(@p1 AS INT) FROM [users] [u] " +
+		"WHERE [u].[age] > (@p2 + 1) AND NOT (a = 1 OR [role] IN ('admin', NULL)) AND -[score]"
+	if sql != expected {
+		t.Errorf("ToSQL() returned `%s` expected `%s`", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"5", 30}) {
+		t.Errorf("ToSQL() returned unexpected args %v", args)
+	}
+
+	q2 := Select().SetDialect(MySQL).AddField(Field{Expr: Lit(`a\b`)})
+	if str := q2.String(); str != `SELECT 'a\\b'` {
+		t.Errorf("String() returned `%s` expected `SELECT 'a\\\\b'`", str)
+	}
+
+	q3 := Select().AddField(Field{Expr: Unary{Op: "-", Operand: Lit(-3)}}, Field{Expr: Unary{Op: "-", Operand: Raw("-x")}}, Field{Expr: Unary{Op: "-", Operand: Lit(3)}})
+	if str := q3.String(); str != "SELECT - -3, - -x, -3" {
+		t.Errorf("String() returned `%s` expected `%s`", str, "SELECT - -3, - -x, -3")
+	}
+}
+
+func Test_LiteralKinds(t *testing.T) {
+	type status string
+	type level int8
+
+	q := Select().AddField(Field{Expr: List{Lit(status("active")), Lit(level(-3)), Lit(uint16(7)), Lit(float32(1.5)), Lit(true)}})
+	if str := q.String(); str != "SELECT ('active', -3, 7, 1.5, TRUE)" {
+		t.Errorf("String() returned `%s` expected `%s`", str, "SELECT ('active', -3, 7, 1.5, TRUE)")
+	}
+
+	q = Select().AddField(Field{Expr: Unary{Operand: Col("score")}})
+	if str := q.String(); str != "SELECT score" {
+		t.Errorf("String() returned `%s` expected `%s`", str, "SELECT score")
+	}
+}
+
+func Test_DialectFunctions(t *testing.T) {
+	q := Select().SetDialect(MySQL).AddField(Field{Expr: Fn("LENGTH", Col("name"))})
+	q.Dialect().Functions["LENGTH"] = "OCTET_LENGTH"
+	if str := q.String(); str != "SELECT LENGTH(`name`)" {
+		t.Errorf("String() returned `%s` expected `%s`", str, "SELECT LENGTH(`name`)")
+	}
+	if _, ok := MySQL.Functions["LENGTH"]; ok {
+		t.Error("Dialect() should return a copy of the function translations")
+	}
+}
@@ -0,0 +1,317 @@
+package squiggle
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// the comparison operators of filter expressions and the ListColumn
+// operators they map to
+var filterOperators = map[string]string{
+	"==":       "eq",
+	"!=":       "ne",
+	"<":        "lt",
+	"=lt=":     "lt",
+	"<=":       "lte",
+	"=le=":     "lte",
+	">":        "gt",
+	"=gt=":     "gt",
+	">=":       "gte",
+	"=ge=":     "gte",
+	"=in=":     "in",
+	"=out=":    "nin",
+	"=like=":   "like",
+	"=isnull=": "null",
+}
+
+// Parses an RSQL style filter expression into criteria with bound values.
+// Comparisons are a column of the schema, an operator and a value or a
+// parenthesized list of values.  ; and "and" combine comparisons with AND
+// logic, , and "or" with OR logic, AND binds tighter than OR and
+// parentheses group.  Values containing reserved characters are quoted with
+// " or ' and use \ to escape.  The operators are == and != (eq, ne), =lt=
+// or <, =le= or <=, =gt= or >, =ge= or >= (lt, lte, gt, gte), =in= and
+// =out= (in, nin), =like= (like) and =isnull= (null) and must be allowed
+// for the column.  Syntax errors and columns or operators the schema doesn't
+// allow are returned as a *ParseError with the offset of the problem, as are
+// parentheses nested deeper than 32 levels.
+//
+// 	schema.ParseFilter("status==active;(age=gt=30,role=in=(admin,owner))")
+// 	// => status = ? AND (age > ? OR role IN (?, ?))
+func (s ListSchema) ParseFilter(filter string) (Criteria, error) {
+	p := &filterParser{src: filter, schema: s}
+	p.skipSpace()
+	if p.pos == len(p.src) {
+		return Criteria{}, nil
+	}
+
+	c, err := p.parseOr()
+	if err != nil {
+		return Criteria{}, err
+	}
+	if p.pos < len(p.src) {
+		return Criteria{}, newParseError(p.src, p.pos, "unexpected %q", p.current())
+	}
+
+	if criteria, ok := c.(Criteria); ok {
+		return criteria, nil
+	}
+	return And(c), nil
+}
+
+// the maximum nesting of parentheses in a filter expression, filters come
+// from users and mustn't be able to exhaust the stack
+const maxFilterDepth = 32
+
+// the state of a filter expression being parsed
+type filterParser struct {
+	src    string
+	pos    int
+	schema ListSchema
+	// the number of parentheses the parser is in
+	depth int
+}
+
+// the characters that end an unquoted column or value
+const filterReserved = "\"'();,=!<>~ \t\r\n"
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// consumes one of the separators and returns whether it was there.  Word
+// separators must be followed by whitespace or a parenthesis.
+func (p *filterParser) accept(separators ...string) bool {
+	p.skipSpace()
+	for _, separator := range separators {
+		if !strings.HasPrefix(p.src[p.pos:], separator) {
+			continue
+		}
+		end := p.pos + len(separator)
+		if separator[0] >= 'a' && separator[0] <= 'z' && end < len(p.src) && strings.IndexByte(" \t\r\n(", p.src[end]) < 0 {
+			continue
+		}
+		p.pos = end
+		p.skipSpace()
+		return true
+	}
+	return false
+}
+
+// parses comparisons combined with OR logic
+func (p *filterParser) parseOr() (interface{}, error) {
+	var items []interface{}
+	for {
+		item, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.accept(",", "or") {
+			break
+		}
+	}
+
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return Or(items...), nil
+}
+
+// parses comparisons combined with AND logic
+func (p *filterParser) parseAnd() (interface{}, error) {
+	var items []interface{}
+	for {
+		item, err := p.parseConstraint()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.accept(";", "and") {
+			break
+		}
+	}
+
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return And(items...), nil
+}
+
+// parses a comparison or a group in parentheses
+func (p *filterParser) parseConstraint() (interface{}, error) {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '(' {
+		start := p.pos
+		if p.depth == maxFilterDepth {
+			return nil, newParseError(p.src, start, "parentheses nested too deeply")
+		}
+		p.pos++
+		p.depth++
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, newParseError(p.src, start, "unclosed parenthesis")
+		}
+		p.depth--
+		return c, nil
+	}
+
+	start := p.pos
+	name := p.scanUnreserved()
+	if name == "" {
+		return nil, p.unexpected("a column")
+	}
+	column, ok := p.schema[name]
+	if !ok {
+		return nil, newParseError(p.src, start, "can't filter by %q", name)
+	}
+
+	p.skipSpace()
+	opStart := p.pos
+	op, ok := p.scanOperator()
+	if !ok {
+		return nil, p.unexpected("an operator")
+	}
+	if !matches(column.Operators, op) {
+		return nil, newParseError(p.src, opStart, "operator %q is not allowed for %q", p.src[opStart:p.pos], name)
+	}
+
+	p.skipSpace()
+	valueStart := p.pos
+	if op == "in" || op == "nin" {
+		values, offsets, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		var list []interface{}
+		for i, value := range values {
+			v, err := column.Type.convert(value)
+			if err != nil {
+				return nil, newParseError(p.src, offsets[i], "%s", err)
+			}
+			list = append(list, v)
+		}
+		if op == "in" {
+			return In(p.schema.column(name), list), nil
+		}
+		return NotIn(p.schema.column(name), list), nil
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	predicate, err := column.predicate(p.schema.column(name), op, value)
+	if err != nil {
+		return nil, newParseError(p.src, valueStart, "%s", err)
+	}
+	return predicate, nil
+}
+
+// scans a comparison operator and returns the ListColumn operator it maps to
+func (p *filterParser) scanOperator() (string, bool) {
+	rest := p.src[p.pos:]
+	if strings.HasPrefix(rest, "=") {
+		if end := strings.IndexByte(rest[1:], '='); end >= 0 {
+			if op, ok := filterOperators[rest[:end+2]]; ok {
+				p.pos += end + 2
+				return op, true
+			}
+		}
+	}
+	for _, symbol := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(rest, symbol) {
+			p.pos += len(symbol)
+			return filterOperators[symbol], true
+		}
+	}
+	return "", false
+}
+
+// parses a parenthesized list of values and returns them with their offsets
+func (p *filterParser) parseList() ([]string, []int, error) {
+	start := p.pos
+	if !p.accept("(") {
+		return nil, nil, p.unexpected("a list of values")
+	}
+
+	var values []string
+	var offsets []int
+	for {
+		p.skipSpace()
+		offset := p.pos
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, nil, err
+		}
+		values = append(values, value)
+		offsets = append(offsets, offset)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.accept(")") {
+		return nil, nil, newParseError(p.src, start, "unclosed parenthesis")
+	}
+
+	return values, offsets, nil
+}
+
+// parses a quoted or unquoted value
+func (p *filterParser) parseValue() (string, error) {
+	p.skipSpace()
+	if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+		start := p.pos
+		quote := p.src[p.pos]
+		var value strings.Builder
+		for p.pos++; p.pos < len(p.src); p.pos++ {
+			switch c := p.src[p.pos]; {
+			case c == '\\' && p.pos+1 < len(p.src):
+				p.pos++
+				value.WriteByte(p.src[p.pos])
+			case c == quote:
+				p.pos++
+				return value.String(), nil
+			default:
+				value.WriteByte(c)
+			}
+		}
+		return "", newParseError(p.src, start, "unterminated string")
+	}
+
+	value := p.scanUnreserved()
+	if value == "" {
+		return "", p.unexpected("a value")
+	}
+	return value, nil
+}
+
+// scans characters up to the next reserved character
+func (p *filterParser) scanUnreserved() string {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte(filterReserved, p.src[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// returns an error for something unexpected where something else was
+// expected
+func (p *filterParser) unexpected(expected string) *ParseError {
+	if p.pos >= len(p.src) {
+		return newParseError(p.src, p.pos, "expected %s but found the end of the filter", expected)
+	}
+	return newParseError(p.src, p.pos, "expected %s but found %q", expected, p.current())
+}
+
+// returns the character at the current position, which may take more than
+// one byte
+func (p *filterParser) current() string {
+	_, size := utf8.DecodeRuneInString(p.src[p.pos:])
+	return p.src[p.pos : p.pos+size]
+}
@@ -0,0 +1,85 @@
+package squiggle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_ParseFilter(t *testing.T) {
+	schema := ListSchema{
+		"status": {Operators: []string{"eq", "ne", "in"}},
+		"age":    {Column: "u.age", Operators: []string{"gt", "lte", "null"}, Type: IntValue},
+		"role":   {Operators: []string{"in", "nin"}},
+		"name":   {Operators: []string{"eq", "like"}},
+	}
+
+	tests := []struct {
+		filter   string
+		expected string
+		args     []interface{}
+	}{
+		{"status==active", "status = ?", []interface{}{"active"}},
+		{"status==active;(age=gt=30,role=in=(admin,owner))", "status = ? AND (u.age > ? OR role IN (?, ?))", []interface{}{"active", int64(30), "admin", "owner"}},
+		{"status!=banned and age<=65 or role=out=(guest)", "(status <> ? AND u.age <= ?) OR role NOT IN (?)", []interface{}{"banned", int64(65), "guest"}},
+		{`name=="Bob \"the\" Builder",name=like='b%'`, "name = ? OR name LIKE ?", []interface{}{`Bob "the" Builder`, "b%"}},
+		{"age=isnull=true", "u.age IS NULL", nil},
+		{"  ", "", nil},
+	}
+	for _, test := range tests {
+		criteria, err := schema.ParseFilter(test.filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) returned error `%s`", test.filter, err)
+			continue
+		}
+		sql, args := criteria.ToSQL()
+		if sql != test.expected {
+			t.Errorf("ParseFilter(%q) returned `%s` expected `%s`", test.filter, sql, test.expected)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("ParseFilter(%q) returned args %v expected %v", test.filter, args, test.args)
+		}
+	}
+}
+
+func Test_ParseFilterErrors(t *testing.T) {
+	schema := ListSchema{
+		"status": {Operators: []string{"eq"}},
+		"age":    {Operators: []string{"gt", "in"}, Type: IntValue},
+	}
+
+	tests := []struct {
+		filter  string
+		offset  int
+		message string
+	}{
+		{"password==x", 0, `can't filter by "password"`},
+		{"status=gt=x", 6, `operator "=gt=" is not allowed for "status"`},
+		{"age=gt=old", 7, `"old" is not an integer`},
+		{"age=in=(1,x)", 10, `"x" is not an integer`},
+		{"age=in=(1, 2, x)", 14, `"x" is not an integer`},
+		{"status~x", 6, `expected an operator but found "~"`},
+		{"status €x", 7, `expected an operator but found "€"`},
+		{"status==", 8, "expected a value but found the end of the filter"},
+		{"(status==a", 0, "unclosed parenthesis"},
+		{"status=='a", 8, "unterminated string"},
+		{"status==a)", 9, `unexpected ")"`},
+		{"status==a;;", 10, `expected a column but found ";"`},
+		{strings.Repeat("(", 40) + "status==a" + strings.Repeat(")", 40), 32, "parentheses nested too deeply"},
+	}
+	for _, test := range tests {
+		_, err := schema.ParseFilter(test.filter)
+		e, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("ParseFilter(%q) returned unexpected error %v", test.filter, err)
+			continue
+		}
+		if e.Offset != test.offset || e.Message != test.message {
+			t.Errorf("ParseFilter(%q) returned error `%s` at %d expected `%s` at %d", test.filter, e.Message, e.Offset, test.message, test.offset)
+		}
+	}
+
+	if _, err := schema.ParseFilter(strings.Repeat("(", 32) + "status==a" + strings.Repeat(")", 32)); err != nil {
+		t.Errorf("ParseFilter() returned error %v for parentheses nested 32 levels", err)
+	}
+}
@@ -0,0 +1,161 @@
+package squiggle
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Returns the shape of a query for grouping metrics: its SQL with every
+// literal, bound value and placeholder, including TRUE, FALSE and NULL
+// other than in IS NULL and the like, replaced by ?, lists of values such
+// as IN lists and the rows of an INSERT collapsed to one (?) and comments
+// removed, along with a stable hash of that SQL.  Queries that differ only in
+// their values share a fingerprint, negative numbers such as -1 are replaced
+// by ? as well.  Middleware is applied first so the fingerprint is that of the
+// SQL sent to the database.  Criteria strings with SQL that can't be
+// tokenized, such as an unterminated string, have an empty shape and the
+// hash 0000000000000000 so their values never end up in metrics, as do
+// queries that can't be rendered, such as those failing middleware.
+//
+// 	squiggle.Select().AddFrom("users").Where(squiggle.In("id", []int{1, 2, 3})).AndWhere("status = 'active'").Limit(10).Fingerprint()
+// 	// => "SELECT * FROM users WHERE (id IN (?)) AND (status = ?) LIMIT ?", "211d873525a2b4a5"
+func (q *Query) Fingerprint() (string, string) {
+	sql, _, err := q.trySQL()
+	if err != nil {
+		return "", "0000000000000000"
+	}
+	dialect := Dialect{Name: q.dialect.Name, IdentifierLeftQuote: q.identifierLeftQuote, IdentifierRightQuote: q.identifierRightQuote}
+	tokens, err := lex(sql, dialect)
+	if err != nil {
+		return "", "0000000000000000"
+	}
+	sql = fingerprintSQL(sql, tokens)
+
+	h := fnv.New64a()
+	h.Write([]byte(sql))
+	return sql, fmt.Sprintf("%016x", h.Sum64())
+}
+
+// a piece of fingerprinted SQL, the kind of its token and whether a space
+// comes before it
+type fingerprintPart struct {
+	text  string
+	kind  tokenKind
+	space bool
+}
+
+// the keywords a minus sign after which is the sign of a number
+var signKeywords = []string{"SELECT", "WHERE", "HAVING", "ON", "AND", "OR", "NOT", "BETWEEN", "IN", "IS", "LIKE", "CASE", "WHEN", "THEN", "ELSE", "LIMIT", "OFFSET", "VALUES"}
+
+// returns SQL with its values replaced and lists collapsed
+func fingerprintSQL(src string, tokens []token) string {
+	var parts []fingerprintPart
+	end := 0
+	for _, t := range tokens[:len(tokens)-1] {
+		part := fingerprintPart{text: src[t.start:t.end], kind: t.kind, space: t.start > end && len(parts) > 0}
+		adjacent := t.start == end
+		end = t.end
+		switch t.kind {
+		case tokenString, tokenNumber, tokenPlaceholder:
+			part.text = "?"
+		case tokenWord:
+			if (t.is("TRUE") || t.is("FALSE") || t.is("NULL")) && !isTest(parts) {
+				part.text = "?"
+			}
+		}
+		if t.kind == tokenNumber && adjacent && isSign(parts) {
+			// -1 is one value
+			part.space = parts[len(parts)-1].space
+			parts = parts[:len(parts)-1]
+		}
+
+		parts = append(parts, part)
+		parts = collapseList(parts)
+		parts = collapseRows(parts)
+	}
+
+	var sql strings.Builder
+	for _, part := range parts {
+		if part.space {
+			sql.WriteString(" ")
+		}
+		sql.WriteString(part.text)
+	}
+	return sql.String()
+}
+
+// reports whether the last part is a minus sign that's the sign of a number
+// following it rather than a subtraction
+func isSign(parts []fingerprintPart) bool {
+	n := len(parts)
+	if n == 0 || parts[n-1].text != "-" {
+		return false
+	}
+	if n == 1 {
+		return true
+	}
+	before := parts[n-2]
+	switch before.kind {
+	case tokenSymbol:
+		return before.text != ")"
+	case tokenWord:
+		return matchesFold(signKeywords, before.text)
+	}
+	return false
+}
+
+// reports whether the last parts are IS or IS NOT, which test for NULL,
+// TRUE or FALSE rather than compare to a value
+func isTest(parts []fingerprintPart) bool {
+	n := len(parts)
+	if n > 0 && strings.EqualFold(parts[n-1].text, "NOT") {
+		n--
+	}
+	return n > 0 && strings.EqualFold(parts[n-1].text, "IS")
+}
+
+// replaces a list of values such as (?, ?, ?) just added to parts by (?)
+func collapseList(parts []fingerprintPart) []fingerprintPart {
+	n := len(parts)
+	if n < 3 || parts[n-1].text != ")" {
+		return parts
+	}
+
+	i := n - 2
+	for ; i > 0; i -= 2 {
+		if parts[i].text != "?" {
+			return parts
+		}
+		if parts[i-1].text == "(" {
+			break
+		}
+		if parts[i-1].text != "," {
+			return parts
+		}
+	}
+	if i <= 0 {
+		return parts
+	}
+	return append(parts[:i-1], fingerprintPart{text: "(?)", space: parts[i-1].space})
+}
+
+// drops a list of values just added to parts when it follows another one,
+// so (?), (?) becomes (?)
+func collapseRows(parts []fingerprintPart) []fingerprintPart {
+	n := len(parts)
+	if n >= 3 && parts[n-1].text == "(?)" && parts[n-2].text == "," && parts[n-3].text == "(?)" {
+		return parts[:n-2]
+	}
+	return parts
+}
+
+// reports whether a name is in a list ignoring case
+func matchesFold(list []string, name string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_Fingerprint(t *testing.T) {
+	tests := []struct {
+		q        *Query
+		expected string
+	}{
+		{Select().AddFrom("users").Where(In("id", []int{1, 2, 3})).AndWhere("status = 'active'").Limit(10).Offset(20), "SELECT * FROM users WHERE (id IN (?)) AND (status = ?) LIMIT ? OFFSET ?"},
+		{Select().SetDialect(PostgreSQL).AddField(Field{Expr: Fn("COALESCE", Col("name"), Lit("none")), Alias: "n"}).AddFrom("users").Where(And(Eq("a", 1), In("b", []int{1, 2}), Gt("c", 2.5))), `SELECT COALESCE("name", ?) AS "n" FROM "users" WHERE "a" = ? AND "b" IN (?) AND "c" > ?`},
+		{Insert("users").Values(map[string]interface{}{"a": 1, "b": 2}, map[string]interface{}{"a": 3, "b": 4}), "INSERT INTO users (a, b) VALUES (?)"},
+		{Select().AddFrom("users").Where("name = 'it''s' /* comment */ AND   age > -1"), "SELECT * FROM users WHERE name = ? AND age > ?"},
+		{Select().AddField(Field{Expr: List{Lit(-3), Lit(2)}}).AddFrom("users").Where("a-1 > 0 AND b BETWEEN -2 AND - 1 AND (c) -1 = 0"), "SELECT (?) FROM users WHERE a-? > ? AND b BETWEEN ? AND - ? AND (c) -? = ?"},
+		{Select().AddField(Field{Expression: "COUNT(*)"}).AddFrom("users").Where("(a, b) IN ((1, 2), (3, 4))"), "SELECT COUNT(*) FROM users WHERE (a, b) IN ((?))"},
+		{Select().AddFrom("users").Where(And(Eq("a", true), "b = FALSE", "c IN (NULL, 1)", IsNull("d"), "e IS NOT TRUE")), "SELECT * FROM users WHERE a = ? AND b = ? AND c IN (?) AND d IS NULL AND e IS NOT TRUE"},
+		{Select().SetDialect(MySQL).AddFrom("users").Where("name = 'it\\'s'"), "SELECT * FROM `users` WHERE name = ?"},
+	}
+	for _, test := range tests {
+		sql, hash := test.q.Fingerprint()
+		if sql != test.expected {
+			t.Errorf("Fingerprint() returned `%s` expected `%s`", sql, test.expected)
+		}
+		if len(hash) != 16 {
+			t.Errorf("Fingerprint() returned unexpected hash %q", hash)
+		}
+	}
+
+	if sql, hash := Select().AddFrom("users").Where("name = 'secret").Fingerprint(); sql != "" || hash != "0000000000000000" {
+		t.Errorf("Fingerprint() returned `%s`, %q for SQL that can't be tokenized", sql, hash)
+	}
+
+	Use(TenantScope("tenant_id", "users"))
+	sql, hash := Select().AddFrom("users").Fingerprint()
+	ResetMiddleware()
+	if sql != "" || hash != "0000000000000000" {
+		t.Errorf("Fingerprint() returned `%s`, %q for a query failing middleware", sql, hash)
+	}
+
+	_, hash1 := Select().AddFrom("users").Where(In("id", []int{1})).Limit(5).Fingerprint()
+	_, hash2 := Select().AddFrom("users").Where(In("id", []int{7, 8, 9})).Limit(50).Fingerprint()
+	_, hash3 := Select().AddFrom("orders").Where(In("id", []int{1})).Limit(5).Fingerprint()
+	if hash1 != hash2 {
+		t.Errorf("queries differing only in values have different hashes %s and %s", hash1, hash2)
+	}
+	if hash1 == hash3 {
+		t.Error("queries of different tables have the same hash")
+	}
+}
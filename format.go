@@ -0,0 +1,122 @@
+package squiggle
+
+import (
+	"strings"
+)
+
+// The casing of the keywords squiggle generates when formatting a query.
+// Criteria strings and expressions are never changed.
+type KeywordCase int
+
+const (
+	UpperCase KeywordCase = iota
+	LowerCase
+)
+
+// Options for Format()
+type FormatOptions struct {
+	// the indentation of one level, two spaces when empty
+	Indent   string
+	Keywords KeywordCase
+}
+
+// the formatting state of a query being formatted
+type formatter struct {
+	options FormatOptions
+	depth   int
+}
+
+// Turns the query into a string of SQL laid out over multiple lines for
+// logging and reading.  Every clause starts on its own line with its items
+// indented below it, nested criteria and subqueries are indented by their
+// depth.
+//
+// 	squiggle.Select().AddField("id", "name").AddFrom("users").
+// 		Where(squiggle.And("a = 1", squiggle.Or("b = 2", "c = 3"))).
+// 		Format(squiggle.FormatOptions{Keywords: squiggle.LowerCase})
+// 	// => select
+// 	//      id,
+// 	//      name
+// 	//    from
+// 	//      users
+// 	//    where
+// 	//      a = 1
+// 	//      and (
+// 	//        b = 2
+// 	//        or c = 3
+// 	//      )
+func (q *Query) Format(options FormatOptions) string {
+	if options.Indent == "" {
+		options.Indent = "  "
+	}
+
+	c := q.Clone()
+	c.formatter = &formatter{options: options}
+
+	w := &sqlWriter{}
+	c.writeSQL(w, "\n")
+	return w.String()
+}
+
+// returns a keyword in the casing of the query's formatting
+func (q *Query) keyword(keyword string) string {
+	if q.formatter != nil && q.formatter.options.Keywords == LowerCase {
+		return strings.ToLower(keyword)
+	}
+	return keyword
+}
+
+// returns the indentation of a level relative to the depth of the query
+func (q *Query) indent(level int) string {
+	return strings.Repeat(q.formatter.options.Indent, q.formatter.depth+level)
+}
+
+// returns the separator between two parts of a clause.  This is a space or,
+// when formatting, a new line indented to the level.
+func (q *Query) line(level int) string {
+	if q.formatter == nil {
+		return " "
+	}
+	return "\n" + q.indent(level)
+}
+
+// returns nothing or, when formatting, a new line indented to the level.
+// This is used inside of parentheses.
+func (q *Query) breakLine(level int) string {
+	if q.formatter == nil {
+		return ""
+	}
+	return "\n" + q.indent(level)
+}
+
+// returns what comes before the item of a clause at index i: a space before
+// the first item and a comma before the others.  When formatting each item
+// is on its own line.
+func (q *Query) listSeparator(i int) string {
+	if q.formatter == nil {
+		if i == 0 {
+			return " "
+		}
+		return ", "
+	}
+	if i == 0 {
+		return "\n" + q.indent(1)
+	}
+	return ",\n" + q.indent(1)
+}
+
+// writes a subquery in parentheses, values it binds are appended to the
+// args of w
+func (q *Query) writeSubquery(w *sqlWriter, subquery *Query) {
+	subquery = subquery.subqueryOf(q)
+	if q.formatter != nil {
+		subquery = subquery.Clone()
+		subquery.formatter = &formatter{options: q.formatter.options, depth: q.formatter.depth + 2}
+	}
+
+	w.write("(")
+	w.write(q.breakLine(2))
+	subquery.toSQL(w)
+	w.write(q.breakLine(1))
+	w.write(")")
+}
@@ -0,0 +1,62 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_Format(t *testing.T) {
+	q1 := Select().AddField("id", "name").AddFrom("users").
+		Where(And("a = 1", Or("b = 2", "c = 3"))).
+		AddOrdering("id").
+		Limit(10)
+
+	expected := `select
+  id,
+  name
+from
+  users
+where
+  a = 1
+  and (
+    b = 2
+    or c = 3
+  )
+order by
+  id asc
+limit 10`
+	if str := q1.Format(FormatOptions{Keywords: LowerCase}); str != expected {
+		t.Errorf("Format() returned\n%s\nexpected\n%s", str, expected)
+	}
+
+	q2 := Select().
+		AddField("country").
+		AddFrom("users").
+		AddJoin(Join{Type: "left", Table: "teams", Alias: "t", On: And("t.id = team_id", "t.active")}).
+		AddGrouping("country").
+		Having(Gt("COUNT(*)", 1)).
+		CountQuery()
+
+	expected = `SELECT
+	COUNT(*)
+FROM
+	(
+		SELECT
+			country
+		FROM
+			users
+		LEFT JOIN teams t
+			ON t.id = team_id
+			AND t.active
+		GROUP BY
+			country
+		HAVING
+			COUNT(*) > ?
+	) count_query`
+	if str := q2.Format(FormatOptions{Indent: "\t"}); str != expected {
+		t.Errorf("Format() returned\n%s\nexpected\n%s", str, expected)
+	}
+
+	if str := q2.String(); str != "SELECT COUNT(*) FROM (SELECT country FROM users LEFT JOIN teams t ON t.id = team_id AND t.active GROUP BY country HAVING COUNT(*) > ?) count_query" {
+		t.Errorf("Format() changed the query `%s`", str)
+	}
+}
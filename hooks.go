@@ -0,0 +1,86 @@
+package squiggle
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Options for SlogHook()
+type SlogOptions struct {
+	// the level queries that succeed are logged at, queries that fail are
+	// logged at slog.LevelError
+	Level slog.Level
+	// returns what is logged for an argument.  When nil every argument is
+	// logged as "[redacted]" so values such as passwords never end up in
+	// logs, use ShowArgs to log them as they are.
+	Redact func(arg interface{}) interface{}
+}
+
+// A Redact function for SlogOptions logging arguments as they are
+func ShowArgs(arg interface{}) interface{} {
+	return arg
+}
+
+// Creates a hook logging every query after it's executed with its SQL,
+// arguments, duration, rows affected and error.
+//
+// 	runner.AddHook(squiggle.SlogHook(logger, squiggle.SlogOptions{Level: slog.LevelDebug}))
+// 	// => level=DEBUG msg=query sql="SELECT * FROM users WHERE id = ?" args=[[redacted]] duration=1.2ms rows=-1
+func SlogHook(logger *slog.Logger, options SlogOptions) Hook {
+	redact := options.Redact
+	if redact == nil {
+		redact = func(interface{}) interface{} { return "[redacted]" }
+	}
+
+	return HookFuncs{AfterFunc: func(ctx context.Context, e *QueryEvent) {
+		args := make([]interface{}, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = redact(arg)
+		}
+		attrs := []slog.Attr{
+			slog.String("sql", e.SQL),
+			slog.Any("args", args),
+			slog.Duration("duration", e.Duration),
+			slog.Int64("rows", e.RowsAffected),
+		}
+
+		level := options.Level
+		if e.Err != nil {
+			level = slog.LevelError
+			attrs = append(attrs, slog.Any("error", e.Err))
+		}
+		logger.LogAttrs(ctx, level, "query", attrs...)
+	}}
+}
+
+// A Tracer starts a span for every query, adapt tracing libraries such as
+// OpenTelemetry to it
+type Tracer interface {
+	// starts a span and returns a context carrying it
+	StartSpan(ctx context.Context, e *QueryEvent) (context.Context, Span)
+}
+
+// A Span of a query started by a Tracer
+type Span interface {
+	// ends the span, the event has the duration, rows affected and error
+	End(e *QueryEvent)
+}
+
+// Creates a hook starting a span with the tracer before every query and
+// ending it afterwards
+func TracingHook(tracer Tracer) Hook {
+	// every hook has its own key so hooks of different tracers can be stacked
+	spanKey := new(int)
+
+	return HookFuncs{
+		BeforeFunc: func(ctx context.Context, e *QueryEvent) context.Context {
+			ctx, span := tracer.StartSpan(ctx, e)
+			return context.WithValue(ctx, spanKey, span)
+		},
+		AfterFunc: func(ctx context.Context, e *QueryEvent) {
+			if span, ok := ctx.Value(spanKey).(Span); ok {
+				span.End(e)
+			}
+		},
+	}
+}
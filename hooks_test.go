@@ -0,0 +1,75 @@
+package squiggle
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type testTracer struct {
+	started []string
+	ended   []error
+}
+
+type testSpan struct {
+	tracer *testTracer
+}
+
+func (t *testTracer) StartSpan(ctx context.Context, e *QueryEvent) (context.Context, Span) {
+	t.started = append(t.started, e.SQL)
+	return ctx, testSpan{tracer: t}
+}
+
+func (s testSpan) End(e *QueryEvent) {
+	s.tracer.ended = append(s.tracer.ended, e.Err)
+}
+
+func Test_SlogHook(t *testing.T) {
+	db, _ := sql.Open("squiggle-fake", "")
+	defer db.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	runner := NewRunner(db, SlogHook(logger, SlogOptions{Level: slog.LevelDebug}))
+
+	runner.Exec(context.Background(), Delete("users").Where(Eq("password", "hunter2")))
+	runner.Exec(context.Background(), Delete("fail").Where(Eq("id", 1)))
+	log := buf.String()
+	for _, expected := range []string{`level=DEBUG msg=query sql="DELETE FROM users WHERE password = ?" args=[[redacted]]`, "rows=1", `level=ERROR msg=query sql="DELETE FROM fail WHERE id = ?"`, `error="query failed"`} {
+		if !strings.Contains(log, expected) {
+			t.Errorf("log is missing `%s`:\n%s", expected, log)
+		}
+	}
+	if strings.Contains(log, "hunter2") {
+		t.Error("SlogHook() logged a redacted argument")
+	}
+
+	buf.Reset()
+	runner = NewRunner(db, SlogHook(logger, SlogOptions{Redact: ShowArgs}))
+	runner.Exec(context.Background(), Delete("users").Where(Eq("id", 42)))
+	if !strings.Contains(buf.String(), "args=[42]") {
+		t.Errorf("ShowArgs did not log the arguments:\n%s", buf.String())
+	}
+}
+
+func Test_TracingHook(t *testing.T) {
+	db, _ := sql.Open("squiggle-fake", "")
+	defer db.Close()
+
+	tracer1, tracer2 := &testTracer{}, &testTracer{}
+	runner := NewRunner(db, TracingHook(tracer1), TracingHook(tracer2))
+	runner.Query(context.Background(), Select().AddFrom("users"))
+	runner.Query(context.Background(), Select().AddFrom("fail"))
+
+	for _, tracer := range []*testTracer{tracer1, tracer2} {
+		if len(tracer.started) != 2 || len(tracer.ended) != 2 {
+			t.Fatalf("tracer started %d and ended %d spans", len(tracer.started), len(tracer.ended))
+		}
+		if tracer.ended[0] != nil || tracer.ended[1] == nil {
+			t.Errorf("spans ended with unexpected errors %v", tracer.ended)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package squiggle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// the identifiers accepted in strict mode without an allowlist
+var safeIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// An IdentifierError is returned by CheckIdentifier() for an identifier that
+// isn't allowed and is what rendering a query in strict mode panics with.
+type IdentifierError struct {
+	Identifier string
+	Reason     string
+}
+
+func (e *IdentifierError) Error() string {
+	return fmt.Sprintf("squiggle: identifier %q %s", e.Identifier, e.Reason)
+}
+
+// Checks an identifier, possibly a dotted name, the way strict mode does.
+// Without an allowlist every part of the identifier must be a letter or
+// underscore followed by letters, digits, underscores or dollar signs.  With
+// an allowlist every part must be in it.  This is useful for checking
+// identifiers taken from user input before building a query.
+//
+// 	squiggle.CheckIdentifier("users.name")
+// 	// => nil
+// 	squiggle.CheckIdentifier("name; DROP TABLE users")
+// 	// => squiggle: identifier "name; DROP TABLE users" does not match the safe pattern
+func CheckIdentifier(identifier string, allowed ...string) error {
+	for _, part := range strings.Split(identifier, ".") {
+		if err := checkIdentifierPart(part, allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checks one part of a dotted identifier
+func checkIdentifierPart(part string, allowed []string) error {
+	if part == "*" {
+		return nil
+	}
+	if len(allowed) > 0 {
+		if !matches(allowed, part) {
+			return &IdentifierError{Identifier: part, Reason: "is not allowed"}
+		}
+		return nil
+	}
+	if !safeIdentifier.MatchString(part) {
+		return &IdentifierError{Identifier: part, Reason: "does not match the safe pattern"}
+	}
+	return nil
+}
+
+// Puts a query in strict mode where every identifier it renders is checked
+// with CheckIdentifier() against the allowlist.  Rendering a query with an
+// identifier that isn't allowed panics with an *IdentifierError.  Criteria
+// strings and expressions given as strings are not checked.  Subqueries
+// are rendered in the strict mode of the query they belong to.
+//
+// 	squiggle.Select().AddFrom("users").AddField("id", "name").StrictIdentifiers("users", "id", "name")
+func (q *Query) StrictIdentifiers(allowed ...string) *Query {
+	q = q.builder()
+	q.strictIdentifiers = true
+	q.allowedIdentifiers = append([]string(nil), allowed...)
+
+	return q
+}
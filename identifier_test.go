@@ -0,0 +1,76 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_identfierQuote(t *testing.T) {
+	tests := []struct {
+		dialect    Dialect
+		identifier string
+		expected   string
+	}{
+		{MySQL, "users", "`users`"},
+		{MySQL, "db1.users", "`db1`.`users`"},
+		{MySQL, "u.*", "`u`.*"},
+		{MySQL, "we`ird", "`we``ird`"},
+		{PostgreSQL, `we"ird`, `"we""ird"`},
+		{SQLServer, "we]i[rd", "[we]]i[rd]"},
+		{Dialect{}, "db1.users", "db1.users"},
+	}
+	for _, test := range tests {
+		q := Select().SetDialect(test.dialect)
+		if str := q.identfierQuote(test.identifier); str != test.expected {
+			t.Errorf("identfierQuote() returned `%s` expected `%s`", str, test.expected)
+		}
+	}
+
+	q1 := Select().SetDialect(PostgreSQL).AddFrom(From{Table: "users", Alias: `u.x"`}).AddField(Field{Name: "id", Alias: "user.id"})
+	expected := `SELECT "id" AS "user.id" FROM "users" "u.x"""`
+	if str := q1.String(); str != expected {
+		t.Errorf("String() returned `%s` expected `%s`", str, expected)
+	}
+}
+
+func Test_CheckIdentifier(t *testing.T) {
+	for _, identifier := range []string{"users", "db1.users", "u.*", "_id", "col$1"} {
+		if err := CheckIdentifier(identifier); err != nil {
+			t.Errorf("CheckIdentifier(%q) returned error `%s`", identifier, err)
+		}
+	}
+	for _, identifier := range []string{"", "1st", "name; DROP TABLE users", "a.", "we`ird", "created at"} {
+		if err := CheckIdentifier(identifier); err == nil {
+			t.Errorf("CheckIdentifier(%q) should return an error", identifier)
+		}
+	}
+	if err := CheckIdentifier("users.name", "users", "name"); err != nil {
+		t.Errorf("CheckIdentifier() returned error `%s`", err)
+	}
+	err := CheckIdentifier("users.password", "users", "name")
+	if e, ok := err.(*IdentifierError); !ok || e.Identifier != "password" {
+		t.Errorf("CheckIdentifier() returned unexpected error %v", err)
+	}
+}
+
+func Test_StrictIdentifiers(t *testing.T) {
+	q1 := Select().AddFrom("users").AddField("id", "name").AddOrdering("name").StrictIdentifiers()
+	if str := q1.String(); str != "SELECT id, name FROM users ORDER BY name ASC" {
+		t.Errorf("String() returned `%s`", str)
+	}
+
+	tests := []*Query{
+		Select().AddFrom("users").AddOrdering("name; DROP TABLE users").StrictIdentifiers(),
+		Select().AddFrom("users").AddField("password").StrictIdentifiers("users", "id"),
+		Select().AddFrom(From{Subquery: Select().AddFrom("secrets"), Alias: "s"}).StrictIdentifiers("users", "s"),
+	}
+	for _, q := range tests {
+		func() {
+			defer func() {
+				if _, ok := recover().(*IdentifierError); !ok {
+					t.Error("rendering an identifier that isn't allowed should panic with an *IdentifierError")
+				}
+			}()
+			_ = q.String()
+		}()
+	}
+}
@@ -0,0 +1,149 @@
+package squiggle
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Create a new INSERT query.  The table may be a string or a squiggle.From
+//
+// 	squiggle.Insert("users")
+// 	squiggle.Insert(squiggle.From{Schema: "db1", Table: "users"})
+func Insert(table interface{}) *Query {
+	q := new(Query)
+	q.queryType = "INSERT"
+
+	switch table.(type) {
+	default:
+		panic(fmt.Sprintf("unexpected type %T used in Insert()", table))
+	case string:
+		q.from = append(q.from, From{Table: table.(string)})
+	case From:
+		q.from = append(q.from, table.(From))
+	}
+
+	return q
+}
+
+// Add rows to an INSERT query.  Each argument is a struct, a pointer to a
+// struct or a map[string]interface{}.  Struct columns are derived from `db`
+// tags: columns tagged readonly are never inserted, columns tagged pk or
+// omitempty are left out when they hold a zero value.  Every row must supply
+// the same columns, a row with different columns panics rather than losing
+// values.
+//
+// 	type User struct {
+// 		ID       int    `db:"id,pk"`
+// 		Username string `db:"username"`
+// 		Created  string `db:"created_at,readonly"`
+// 	}
+// 	squiggle.Insert("users").Values(User{Username: "bob"}, User{Username: "al"})
+// 	// => INSERT INTO users (username) VALUES (?), (?)
+func (q *Query) Values(rows ...interface{}) *Query {
+	q = q.builder()
+	for _, row := range rows {
+		columns, values := rowValues(row)
+		if len(q.insertRows) == 0 {
+			q.insertColumns = columns
+		} else if !sameColumns(q.insertColumns, columns) {
+			panic(fmt.Sprintf("row with columns %v used in Values() expected columns %v", columns, q.insertColumns))
+		}
+
+		var ordered []interface{}
+		for _, column := range q.insertColumns {
+			ordered = append(ordered, values[column])
+		}
+		q.insertRows = append(q.insertRows, ordered)
+	}
+
+	return q
+}
+
+// returns true when both lists hold the same columns in any order
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, column := range a {
+		seen[column] = true
+	}
+	for _, column := range b {
+		if !seen[column] {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the columns to insert for a row and the value of every column the
+// row has
+func rowValues(row interface{}) ([]string, map[string]interface{}) {
+	var columns []string
+	values := map[string]interface{}{}
+
+	if m, ok := row.(map[string]interface{}); ok {
+		for column, value := range m {
+			columns = append(columns, column)
+			values[column] = value
+		}
+		sort.Strings(columns)
+		return columns, values
+	}
+
+	rv, ok := structValue(row)
+	if !ok {
+		panic(fmt.Sprintf("unexpected type %T used in Values()", row))
+	}
+	for _, column := range getStructInfo(rv.Type()).columns {
+		if column.readOnly {
+			continue
+		}
+		fv, ok := column.value(rv)
+		if !ok {
+			continue
+		}
+		values[column.name] = fv.Interface()
+		if (column.omitEmpty || column.pk) && fv.IsZero() {
+			continue
+		}
+		columns = append(columns, column.name)
+	}
+
+	return columns, values
+}
+
+// writes an INSERT query
+func (q *Query) insertSQL(w *sqlWriter) {
+	w.write(q.keyword("INSERT INTO"))
+	if len(q.from) > 0 {
+		w.write(" ")
+		q.writeQualified(w, q.from[0].Schema, "", q.from[0].Table)
+	}
+	if len(q.insertRows) == 0 {
+		return
+	}
+
+	w.write(" (")
+	for i, column := range q.insertColumns {
+		if i > 0 {
+			w.write(", ")
+		}
+		q.writeIdentifier(w, column)
+	}
+	w.write(")")
+
+	w.write(q.line(0))
+	w.write(q.keyword("VALUES"))
+	for i, row := range q.insertRows {
+		w.write(q.listSeparator(i))
+		w.write("(")
+		for j, value := range row {
+			if j > 0 {
+				w.write(", ")
+			}
+			q.bind(w, value)
+		}
+		w.write(")")
+	}
+}
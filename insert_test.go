@@ -0,0 +1,51 @@
+package squiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Insert(t *testing.T) {
+	q := Insert(From{Schema: "db1", Table: "users"})
+	if q.queryType != "INSERT" {
+		t.Errorf("query type for Insert() should be \"INSERT\"")
+	}
+	if len(q.from) != 1 || q.from[0].Table != "users" {
+		t.Error("Insert() did not set the table")
+	}
+}
+
+func Test_Values(t *testing.T) {
+	q1 := Insert("users").
+		SetIdentifierQuotes(`"`).
+		SetPlaceholder("$").
+		Values(testUser{Username: "bob", Nickname: "b", CreatedAt: "now"}, &testUser{Username: "al"})
+
+	sql, args := q1.ToSQL()
+	expected := `INSERT INTO "users" ("username", "Nickname") VALUES ($1, $2), ($3, $4)`
+	if sql != expected {
+		t.Errorf("ToSQL() returned `%s` expected `%s`", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"bob", "b", "al", ""}) {
+		t.Errorf("ToSQL() returned unexpected args %v", args)
+	}
+
+	q2 := Insert("users").Values(map[string]interface{}{"username": "bob", "id": 1})
+	sql, args = q2.ToSQL()
+	expected = "INSERT INTO users (id, username) VALUES (?, ?)"
+	if sql != expected {
+		t.Errorf("ToSQL() returned `%s` expected `%s`", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "bob"}) {
+		t.Errorf("ToSQL() returned unexpected args %v", args)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Values() with different columns did not panic")
+			}
+		}()
+		Insert("users").Values(testUser{Username: "bob"}, &testUser{ID: 3, Username: "al", Email: "al@example.com"})
+	}()
+}
@@ -0,0 +1,535 @@
+package squiggle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// The version of the JSON encoding of queries.  It's increased whenever the
+// encoding changes in a way older versions of squiggle can't read, queries
+// encoded by older versions can always be decoded.
+const JSONVersion = 1
+
+// the JSON encoding of a query
+type jsonQuery struct {
+	Version            int                 `json:"version"`
+	Type               string              `json:"type"`
+	Distinct           bool                `json:"distinct,omitempty"`
+	Fields             []Field             `json:"fields,omitempty"`
+	From               []From              `json:"from,omitempty"`
+	Joins              []Join              `json:"joins,omitempty"`
+	Where              *Criteria           `json:"where,omitempty"`
+	Groupings          []Grouping          `json:"groupings,omitempty"`
+	Having             *Criteria           `json:"having,omitempty"`
+	Orderings          []Ordering          `json:"orderings,omitempty"`
+	Limit              int                 `json:"limit,omitempty"`
+	Offset             int                 `json:"offset,omitempty"`
+	Dialect            string              `json:"dialect,omitempty"`
+	CustomDialect      *jsonDialect        `json:"customDialect,omitempty"`
+	IdentifierQuotes   []string            `json:"identifierQuotes,omitempty"`
+	Placeholder        string              `json:"placeholder,omitempty"`
+	InsertColumns      []string            `json:"insertColumns,omitempty"`
+	InsertRows         [][]json.RawMessage `json:"insertRows,omitempty"`
+	HardDelete         bool                `json:"hardDelete,omitempty"`
+	StrictIdentifiers  bool                `json:"strictIdentifiers,omitempty"`
+	AllowedIdentifiers []string            `json:"allowedIdentifiers,omitempty"`
+	StrictValidation   bool                `json:"strictValidation,omitempty"`
+	Tags               map[string]string   `json:"tags,omitempty"`
+	CommentAt          string              `json:"commentAt,omitempty"`
+}
+
+// the JSON encoding of a dialect that isn't one of the predefined dialects
+type jsonDialect struct {
+	Name             string            `json:"name,omitempty"`
+	IdentifierQuotes []string          `json:"identifierQuotes,omitempty"`
+	Placeholder      string            `json:"placeholder,omitempty"`
+	Functions        map[string]string `json:"functions,omitempty"`
+}
+
+// returns the predefined dialect with a name
+func predefinedDialect(name string) (Dialect, bool) {
+	for _, dialect := range []Dialect{MySQL, PostgreSQL, SQLite, SQLServer} {
+		if dialect.Name == name {
+			return dialect, true
+		}
+	}
+	return Dialect{}, false
+}
+
+// Encodes a query as JSON, for example to save a report a user built.  The
+// encoding is versioned, see JSONVersion.  Predefined dialects are encoded
+// by name, other dialects with their quotes, placeholder and functions.
+// Tags and the position of their comment are encoded, middleware and values
+// attached to the query are not.  Values bound to placeholders are encoded
+// as JSON values, so when the query is decoded integers come back as int64,
+// other numbers as float64 and times as strings.
+func (q *Query) MarshalJSON() ([]byte, error) {
+	j := jsonQuery{
+		Version:            JSONVersion,
+		Type:               q.queryType,
+		Distinct:           q.distinct,
+		Fields:             q.fields,
+		From:               q.from,
+		Joins:              q.joins,
+		Groupings:          q.groupings,
+		Orderings:          q.orderings,
+		Limit:              q.limit,
+		Offset:             q.offset,
+		Placeholder:        q.placeholder,
+		InsertColumns:      q.insertColumns,
+		HardDelete:         q.hardDelete,
+		StrictIdentifiers:  q.strictIdentifiers,
+		AllowedIdentifiers: q.allowedIdentifiers,
+		StrictValidation:   q.strictValidation,
+		Tags:               q.tags,
+	}
+	if q.commentPosition == CommentStart {
+		j.CommentAt = "start"
+	}
+	if len(q.where.expressions) > 0 {
+		j.Where = &q.where
+	}
+	if len(q.having.expressions) > 0 {
+		j.Having = &q.having
+	}
+	if dialect, ok := predefinedDialect(q.dialect.Name); ok && reflect.DeepEqual(dialect, q.dialect) {
+		j.Dialect = q.dialect.Name
+	} else if !reflect.DeepEqual(q.dialect, Dialect{}) {
+		j.CustomDialect = &jsonDialect{Name: q.dialect.Name, Placeholder: q.dialect.Placeholder, Functions: q.dialect.Functions}
+		if q.dialect.IdentifierLeftQuote != "" || q.dialect.IdentifierRightQuote != "" {
+			j.CustomDialect.IdentifierQuotes = []string{q.dialect.IdentifierLeftQuote, q.dialect.IdentifierRightQuote}
+		}
+	}
+	if q.identifierLeftQuote != "" || q.identifierRightQuote != "" {
+		j.IdentifierQuotes = []string{q.identifierLeftQuote, q.identifierRightQuote}
+	}
+	for _, row := range q.insertRows {
+		var values []json.RawMessage
+		for _, value := range row {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, raw)
+		}
+		j.InsertRows = append(j.InsertRows, values)
+	}
+
+	return json.Marshal(j)
+}
+
+// Decodes a query encoded by MarshalJSON().  Dialects encoded by a name that
+// isn't a predefined dialect are rejected.
+//
+// 	q := new(squiggle.Query)
+// 	err := json.Unmarshal(saved, q)
+func (q *Query) UnmarshalJSON(data []byte) error {
+	var j jsonQuery
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Version < 1 || j.Version > JSONVersion {
+		return fmt.Errorf("squiggle: unsupported query version %d", j.Version)
+	}
+
+	*q = Query{
+		queryType:          j.Type,
+		distinct:           j.Distinct,
+		fields:             j.Fields,
+		from:               j.From,
+		joins:              j.Joins,
+		groupings:          j.Groupings,
+		orderings:          j.Orderings,
+		limit:              j.Limit,
+		offset:             j.Offset,
+		placeholder:        j.Placeholder,
+		insertColumns:      j.InsertColumns,
+		hardDelete:         j.HardDelete,
+		strictIdentifiers:  j.StrictIdentifiers,
+		allowedIdentifiers: j.AllowedIdentifiers,
+		strictValidation:   j.StrictValidation,
+		tags:               j.Tags,
+	}
+	switch j.CommentAt {
+	case "", "end":
+	case "start":
+		q.commentPosition = CommentStart
+	default:
+		return fmt.Errorf("squiggle: unknown comment position %q", j.CommentAt)
+	}
+	if j.Where != nil {
+		q.where = *j.Where
+	}
+	if j.Having != nil {
+		q.having = *j.Having
+	}
+	if j.Dialect != "" {
+		dialect, ok := predefinedDialect(j.Dialect)
+		if !ok {
+			return fmt.Errorf("squiggle: unknown dialect %q", j.Dialect)
+		}
+		q.dialect = dialect
+		q.dialect.Functions = copyFunctions(dialect.Functions)
+	}
+	if d := j.CustomDialect; d != nil {
+		q.dialect = Dialect{Name: d.Name, Placeholder: d.Placeholder, Functions: d.Functions}
+		switch len(d.IdentifierQuotes) {
+		case 0:
+		case 2:
+			q.dialect.IdentifierLeftQuote, q.dialect.IdentifierRightQuote = d.IdentifierQuotes[0], d.IdentifierQuotes[1]
+		default:
+			return fmt.Errorf("squiggle: unexpected identifier quotes %q", d.IdentifierQuotes)
+		}
+	}
+	switch len(j.IdentifierQuotes) {
+	case 0:
+	case 2:
+		q.identifierLeftQuote, q.identifierRightQuote = j.IdentifierQuotes[0], j.IdentifierQuotes[1]
+	default:
+		return fmt.Errorf("squiggle: unexpected identifier quotes %q", j.IdentifierQuotes)
+	}
+	for _, row := range j.InsertRows {
+		var values []interface{}
+		for _, raw := range row {
+			value, err := decodeJSONValue(raw)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		q.insertRows = append(q.insertRows, values)
+	}
+
+	return nil
+}
+
+// the JSON encoding of a field
+type jsonField struct {
+	Schema     string    `json:"schema,omitempty"`
+	Table      string    `json:"table,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Expression string    `json:"expression,omitempty"`
+	Expr       *jsonNode `json:"expr,omitempty"`
+	Alias      string    `json:"alias,omitempty"`
+}
+
+// Encodes a field as JSON including its expression tree
+func (f Field) MarshalJSON() ([]byte, error) {
+	j := jsonField{Schema: f.Schema, Table: f.Table, Name: f.Name, Expression: f.Expression, Alias: f.Alias}
+	if f.Expr != nil {
+		node, err := encodeNode(f.Expr)
+		if err != nil {
+			return nil, err
+		}
+		j.Expr = node
+	}
+	return json.Marshal(j)
+}
+
+// Decodes a field encoded by MarshalJSON()
+func (f *Field) UnmarshalJSON(data []byte) error {
+	var j jsonField
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*f = Field{Schema: j.Schema, Table: j.Table, Name: j.Name, Expression: j.Expression, Alias: j.Alias}
+	if j.Expr != nil {
+		e, err := decodeExpression(j.Expr)
+		if err != nil {
+			return err
+		}
+		f.Expr = e
+	}
+	return nil
+}
+
+// Encodes criteria as JSON.  Strings, predicates, nested criteria and
+// expression trees are encoded as nodes tagged with their kind, empty
+// criteria are encoded as null.
+//
+// 	json.Marshal(squiggle.And("a = 1", squiggle.Eq("b", 2)))
+// 	// => {"and":true,"expressions":[{"kind":"sql","sql":"a = 1"},{"kind":"predicate","field":"b","op":"=","value":2}]}
+func (c Criteria) MarshalJSON() ([]byte, error) {
+	if len(c.expressions) == 0 {
+		return []byte("null"), nil
+	}
+
+	node, err := encodeNode(c)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonCriteria{And: c.and, Expressions: node.Expressions})
+}
+
+// Decodes criteria encoded by MarshalJSON()
+func (c *Criteria) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*c = Criteria{}
+		return nil
+	}
+
+	var j jsonCriteria
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	criteria, err := decodeNode(&jsonNode{Kind: "criteria", And: j.And, Expressions: j.Expressions})
+	if err != nil {
+		return err
+	}
+	*c = criteria.(Criteria)
+	return nil
+}
+
+// the JSON encoding of criteria
+type jsonCriteria struct {
+	And         bool        `json:"and"`
+	Expressions []*jsonNode `json:"expressions"`
+}
+
+// the JSON encoding of a criteria string, criteria, predicate or expression
+type jsonNode struct {
+	Kind        string          `json:"kind"`
+	SQL         string          `json:"sql,omitempty"`
+	And         bool            `json:"and,omitempty"`
+	Expressions []*jsonNode     `json:"expressions,omitempty"`
+	Schema      string          `json:"schema,omitempty"`
+	Table       string          `json:"table,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	Field       string          `json:"field,omitempty"`
+	Op          string          `json:"op,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Value       json.RawMessage `json:"value,omitempty"`
+	Args        []*jsonNode     `json:"args,omitempty"`
+	Left        *jsonNode       `json:"left,omitempty"`
+	Right       *jsonNode       `json:"right,omitempty"`
+	Operand     *jsonNode       `json:"operand,omitempty"`
+	Whens       []jsonWhen      `json:"whens,omitempty"`
+	Else        *jsonNode       `json:"else,omitempty"`
+	Expr        *jsonNode       `json:"expr,omitempty"`
+	Items       []*jsonNode     `json:"items,omitempty"`
+}
+
+// the JSON encoding of a branch of a CASE expression
+type jsonWhen struct {
+	Cond   *jsonNode `json:"cond"`
+	Result *jsonNode `json:"result"`
+}
+
+// encodes a criteria string, criteria, predicate or expression
+func encodeNode(node interface{}) (*jsonNode, error) {
+	var err error
+	encode := func(node interface{}) *jsonNode {
+		if err != nil || node == nil {
+			return nil
+		}
+		var n *jsonNode
+		n, err = encodeNode(node)
+		return n
+	}
+	value := func(v interface{}) json.RawMessage {
+		if err != nil {
+			return nil
+		}
+		var raw []byte
+		raw, err = json.Marshal(v)
+		return raw
+	}
+
+	var n *jsonNode
+	switch e := node.(type) {
+	default:
+		return nil, fmt.Errorf("squiggle: can't encode %T as JSON", node)
+	case string:
+		n = &jsonNode{Kind: "sql", SQL: e}
+	case Criteria:
+		n = &jsonNode{Kind: "criteria", And: e.and}
+		for _, expression := range e.expressions {
+			n.Expressions = append(n.Expressions, encode(expression))
+		}
+	case Predicate:
+		n = &jsonNode{Kind: "predicate", Schema: e.Schema, Table: e.Table, Field: e.Field, Op: e.Op, Value: value(e.Value)}
+	case Column:
+		n = &jsonNode{Kind: "column", Schema: e.Schema, Table: e.Table, Name: e.Name}
+	case Literal:
+		n = &jsonNode{Kind: "literal", Value: value(e.Value)}
+	case Param:
+		n = &jsonNode{Kind: "param", Value: value(e.Value)}
+	case Raw:
+		n = &jsonNode{Kind: "raw", SQL: string(e)}
+	case Func:
+		n = &jsonNode{Kind: "func", Name: e.Name}
+		for _, arg := range e.Args {
+			n.Args = append(n.Args, encode(arg))
+		}
+	case Binary:
+		n = &jsonNode{Kind: "binary", Left: encode(e.Left), Op: e.Op, Right: encode(e.Right)}
+	case Unary:
+		n = &jsonNode{Kind: "unary", Op: e.Op, Operand: encode(e.Operand)}
+	case Case:
+		n = &jsonNode{Kind: "case", Operand: encode(e.Operand), Else: encode(e.Else)}
+		for _, when := range e.Whens {
+			n.Whens = append(n.Whens, jsonWhen{Cond: encode(when.Cond), Result: encode(when.Result)})
+		}
+	case Cast:
+		n = &jsonNode{Kind: "cast", Expr: encode(e.Expr), Type: e.Type}
+	case List:
+		n = &jsonNode{Kind: "list"}
+		for _, item := range e {
+			n.Items = append(n.Items, encode(item))
+		}
+	case Fragment:
+		n = &jsonNode{Kind: "fragment"}
+		for _, item := range e {
+			n.Items = append(n.Items, encode(item))
+		}
+	}
+
+	return n, err
+}
+
+// decodes a node that must be an expression
+func decodeExpression(n *jsonNode) (Expression, error) {
+	if n == nil {
+		return nil, nil
+	}
+	node, err := decodeNode(n)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := node.(Expression)
+	if !ok {
+		return nil, fmt.Errorf("squiggle: unexpected %s node in an expression", n.Kind)
+	}
+	return e, nil
+}
+
+// decodes a criteria string, criteria, predicate or expression
+func decodeNode(n *jsonNode) (interface{}, error) {
+	if n == nil {
+		return nil, fmt.Errorf("squiggle: missing node")
+	}
+
+	var err error
+	decode := func(n *jsonNode) Expression {
+		if err != nil || n == nil {
+			return nil
+		}
+		var e Expression
+		e, err = decodeExpression(n)
+		return e
+	}
+	// decodes an operand the node can't be rendered without
+	require := func(operand *jsonNode, name string) Expression {
+		if err == nil && operand == nil {
+			err = fmt.Errorf("squiggle: %s node without %s", n.Kind, name)
+		}
+		return decode(operand)
+	}
+	value := func(raw json.RawMessage) interface{} {
+		if err != nil || len(raw) == 0 {
+			return nil
+		}
+		var v interface{}
+		v, err = decodeJSONValue(raw)
+		return v
+	}
+
+	var node interface{}
+	switch n.Kind {
+	default:
+		return nil, fmt.Errorf("squiggle: unknown node kind %q", n.Kind)
+	case "sql":
+		node = n.SQL
+	case "criteria":
+		c := Criteria{and: n.And}
+		for _, expression := range n.Expressions {
+			var e interface{}
+			if e, err = decodeNode(expression); err != nil {
+				return nil, err
+			}
+			c.expressions = append(c.expressions, e)
+		}
+		node = c
+	case "predicate":
+		node = Predicate{Schema: n.Schema, Table: n.Table, Field: n.Field, Op: n.Op, Value: value(n.Value)}
+	case "column":
+		node = Column{Schema: n.Schema, Table: n.Table, Name: n.Name}
+	case "literal":
+		node = Literal{Value: value(n.Value)}
+	case "param":
+		node = Param{Value: value(n.Value)}
+	case "raw":
+		node = Raw(n.SQL)
+	case "func":
+		f := Func{Name: n.Name}
+		for _, arg := range n.Args {
+			f.Args = append(f.Args, require(arg, "argument"))
+		}
+		node = f
+	case "binary":
+		node = Binary{Left: require(n.Left, "left"), Op: n.Op, Right: require(n.Right, "right")}
+	case "unary":
+		node = Unary{Op: n.Op, Operand: require(n.Operand, "operand")}
+	case "case":
+		c := Case{Operand: decode(n.Operand), Else: decode(n.Else)}
+		if len(n.Whens) == 0 && err == nil {
+			err = fmt.Errorf("squiggle: case node without whens")
+		}
+		for _, when := range n.Whens {
+			c.Whens = append(c.Whens, When{Cond: require(when.Cond, "cond"), Result: require(when.Result, "result")})
+		}
+		node = c
+	case "cast":
+		node = Cast{Expr: require(n.Expr, "expr"), Type: n.Type}
+	case "list":
+		var l List
+		for _, item := range n.Items {
+			l = append(l, require(item, "item"))
+		}
+		node = l
+	case "fragment":
+		var f Fragment
+		for _, item := range n.Items {
+			f = append(f, require(item, "item"))
+		}
+		node = f
+	}
+
+	return node, err
+}
+
+// decodes a JSON value bound to a placeholder or rendered as a literal.
+// Integers are decoded as int64 and other numbers as float64.
+func decodeJSONValue(raw json.RawMessage) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertJSONNumbers(v), nil
+}
+
+// replaces json.Numbers with int64 or float64
+func convertJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertJSONNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertJSONNumbers(item)
+		}
+	}
+	return v
+}
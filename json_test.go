@@ -0,0 +1,114 @@
+package squiggle
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_QueryJSON(t *testing.T) {
+	custom := Dialect{Name: "custom", IdentifierLeftQuote: "`", IdentifierRightQuote: "`", Placeholder: ":", Functions: map[string]string{"LEN": "LENGTH"}}
+	tests := []struct {
+		q    *Query
+		args []interface{}
+	}{
+		{Select().SetDialect(PostgreSQL).Distinct().
+			AddField("id", Field{Table: "u", Name: "name", Alias: "n"}, Field{Expression: "COUNT(*)", Alias: "c"},
+				Field{Expr: Case{Whens: []When{{Cond: Op(Col("u.age"), ">", Arg(17)), Result: Lit("adult")}}, Else: Lit("minor")}, Alias: "age_group"}).
+			AddFrom(From{Schema: "app", Table: "users", Alias: "u"}, From{Subquery: Select().AddFrom("roles").Where(Eq("active", true)), Alias: "r"}).
+			AddJoin(Join{Type: "left", Table: "orders", Alias: "o", On: And("o.user_id = u.id", Gt("o.total", 9.5))}).
+			Where(Or(And(Eq("u.status", "active"), In("u.role", []string{"admin", "owner"})), IsNull("u.deleted_at"), Not(Like("u.name", "x%")))).
+			AddGrouping("id", Grouping{Table: "u", Field: "name"}).
+			Having("COUNT(*) > 1").
+			AddOrdering(Ordering{Field: "name", Desc: true, Nulls: "last"}).
+			Limit(10).Offset(20),
+			[]interface{}{int64(17), true, 9.5, "active", "admin", "owner", "x%"}},
+		{Insert(From{Schema: "app", Table: "users"}).SetIdentifierQuotes("[", "]").Values(map[string]interface{}{"name": "bob", "age": 30}), []interface{}{int64(30), "bob"}},
+		{Delete("users").Where(Eq("id", 1)).HardDelete(), []interface{}{int64(1)}},
+		{Select().AddFrom("users").AddField("id").StrictIdentifiers("users", "id"), nil},
+		{Select().AddFrom("users").Tag("route", "/users").Tag("app", "billing").CommentAt(CommentStart), nil},
+		{Select().AddFrom("users").Tag("route", "/users"), nil},
+		{Select().SetDialect(custom).AddField(Field{Expr: Fn("LEN", Col("name"))}).AddFrom("users").Where(Eq("id", 2.5)), []interface{}{2.5}},
+		{Select().AddField(Field{Expr: Fragment{Raw("price * "), Arg(2)}, Alias: "double"}).AddFrom("items"), []interface{}{int64(2)}},
+	}
+
+	for _, test := range tests {
+		q1 := test.q
+		data, err := json.Marshal(q1)
+		if err != nil {
+			t.Fatalf("json.Marshal() returned error `%s`", err)
+		}
+		q2 := new(Query)
+		if err := json.Unmarshal(data, q2); err != nil {
+			t.Fatalf("json.Unmarshal() returned error `%s` for %s", err, data)
+		}
+
+		sql1, args1 := q1.ToSQL()
+		sql2, args2 := q2.ToSQL()
+		if sql1 != sql2 {
+			t.Errorf("decoded query renders `%s` expected `%s`", sql2, sql1)
+		}
+		if len(args1) != len(test.args) || !reflect.DeepEqual(args2, test.args) {
+			t.Errorf("decoded query binds %#v expected %#v", args2, test.args)
+		}
+		if !reflect.DeepEqual(q1.Dialect(), q2.Dialect()) {
+			t.Errorf("decoded query has dialect %+v expected %+v", q2.Dialect(), q1.Dialect())
+		}
+		if !reflect.DeepEqual(q1.Tags(), q2.Tags()) || q1.commentPosition != q2.commentPosition {
+			t.Errorf("decoded query has tags %v at %d expected %v at %d", q2.Tags(), q2.commentPosition, q1.Tags(), q1.commentPosition)
+		}
+	}
+}
+
+func Test_CriteriaJSON(t *testing.T) {
+	data, err := json.Marshal(And("a = 1", Eq("b", 2), Or("c = 3", "d = 4")))
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error `%s`", err)
+	}
+	expected := `{"and":true,"expressions":[{"kind":"sql","sql":"a = 1"},{"kind":"predicate","field":"b","op":"=","value":2},{"kind":"criteria","expressions":[{"kind":"sql","sql":"c = 3"},{"kind":"sql","sql":"d = 4"}]}]}`
+	if string(data) != expected {
+		t.Errorf("json.Marshal() returned `%s` expected `%s`", data, expected)
+	}
+
+	var c Criteria
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("json.Unmarshal() returned error `%s`", err)
+	}
+	sql, args := c.ToSQL()
+	if sql != "a = 1 AND b = ? AND (c = 3 OR d = 4)" {
+		t.Errorf("decoded criteria renders `%s`", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{int64(2)}) {
+		t.Errorf("decoded criteria binds %v", args)
+	}
+
+	if data, _ := json.Marshal(Criteria{}); string(data) != "null" {
+		t.Errorf("json.Marshal() returned `%s` for empty criteria", data)
+	}
+}
+
+func Test_QueryJSONErrors(t *testing.T) {
+	tests := []string{
+		`{"version":2,"type":"SELECT"}`,
+		`{"type":"SELECT"}`,
+		`{"version":1,"type":"SELECT","where":{"and":true,"expressions":[{"kind":"bogus"}]}}`,
+		`{"version":1,"type":"SELECT","fields":[{"expr":{"kind":"sql","sql":"a"}}]}`,
+		`{"version":1,"type":"SELECT","identifierQuotes":["["]}`,
+		`{"version":1,"type":"SELECT","dialect":"oracle"}`,
+		`{"version":1,"type":"SELECT","commentAt":"middle"}`,
+		`{"version":1,"type":"SELECT","fields":[{"expr":{"kind":"binary","op":"+","right":{"kind":"literal","value":1}}}]}`,
+		`{"version":1,"type":"SELECT","fields":[{"expr":{"kind":"unary","op":"-"}}]}`,
+		`{"version":1,"type":"SELECT","fields":[{"expr":{"kind":"cast","type":"INT"}}]}`,
+		`{"version":1,"type":"SELECT","fields":[{"expr":{"kind":"case"}}]}`,
+		`{"version":1,"type":"SELECT","fields":[{"expr":{"kind":"list","items":[null]}}]}`,
+	}
+	for _, test := range tests {
+		if err := json.Unmarshal([]byte(test), new(Query)); err == nil {
+			t.Errorf("json.Unmarshal() should return an error for %s", test)
+		}
+	}
+
+	if _, err := json.Marshal(Select().Where(Eq("a", make(chan int)))); err == nil {
+		t.Error("json.Marshal() should return an error for values it can't encode")
+	}
+}
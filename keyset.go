@@ -0,0 +1,135 @@
+package squiggle
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Add keyset (cursor) pagination criteria to a query.  The values are the
+// last seen values of the query's orderings, one value per ordering in the
+// same order.  The criteria select the rows that come after those values in
+// the ordering of the query and are added with AndWhere().  Mixed ASC and
+// DESC orderings are supported.  A nil value means the last row had NULL for
+// that field.  NULLs are treated as larger than any other value unless
+// Ordering.Nulls says otherwise, which is how PostgreSQL sorts by default.
+// The orderings should end with a unique field, such as the primary key, so
+// that no rows are skipped.
+//
+// 	squiggle.Select().AddFrom("posts").
+// 		AddOrdering(squiggle.Ordering{Field: "created_at", Desc: true}, "id").
+// 		After("2015-01-01", 10).
+// 		Limit(20)
+// 	// => SELECT * FROM posts WHERE created_at < ? OR (created_at = ? AND (id > ? OR id IS NULL)) ORDER BY created_at DESC, id ASC LIMIT 20
+func (q *Query) After(values ...interface{}) *Query {
+	if len(values) != len(q.orderings) {
+		panic(fmt.Sprintf("After() expects %d values for the orderings of the query, got %d", len(q.orderings), len(values)))
+	}
+
+	var terms []interface{}
+	for i, ordering := range q.orderings {
+		after, ok := afterCriteria(ordering, values[i])
+		if !ok {
+			continue
+		}
+
+		term := Criteria{and: true}
+		for j := 0; j < i; j++ {
+			term.expressions = append(term.expressions, orderingPredicate(q.orderings[j], Eq(q.orderings[j].Field, values[j])))
+		}
+		term.expressions = append(term.expressions, after)
+		if len(term.expressions) == 1 {
+			terms = append(terms, after)
+		} else {
+			terms = append(terms, term)
+		}
+	}
+
+	if len(terms) == 0 {
+		// the cursor is at the very end of the ordering
+		return q.AndWhere("1 = 0")
+	}
+	return q.AndWhere(Or(terms...))
+}
+
+// returns the criteria matching values that come strictly after the value in
+// an ordering, ok is false when no value can come after it
+func afterCriteria(ordering Ordering, value interface{}) (interface{}, bool) {
+	nullsFirst := strings.ToUpper(ordering.Nulls) == "FIRST" || (ordering.Nulls == "" && ordering.Desc)
+
+	if isNil(value) {
+		if nullsFirst {
+			return orderingPredicate(ordering, IsNotNull(ordering.Field)), true
+		}
+		return nil, false
+	}
+
+	var after Predicate
+	if ordering.Desc {
+		after = orderingPredicate(ordering, Lt(ordering.Field, value))
+	} else {
+		after = orderingPredicate(ordering, Gt(ordering.Field, value))
+	}
+	if nullsFirst {
+		return after, true
+	}
+	return Or(after, orderingPredicate(ordering, IsNull(ordering.Field))), true
+}
+
+// qualifies a predicate with the schema and table of an ordering
+func orderingPredicate(ordering Ordering, p Predicate) Predicate {
+	p.Schema = ordering.Schema
+	p.Table = ordering.Table
+	return p
+}
+
+// Encodes the last seen values of a page into an opaque cursor string that
+// can be handed to API clients
+//
+// 	cursor, err := squiggle.EncodeCursor(lastPost.CreatedAt, lastPost.ID)
+func EncodeCursor(values ...interface{}) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decodes a cursor created by EncodeCursor() into values that can be passed
+// to After().  Whole numbers are decoded as int64, other numbers as float64
+// and times as strings.
+//
+// 	values, err := squiggle.DecodeCursor(r.URL.Query().Get("cursor"))
+// 	if err != nil {
+// 		// bad request
+// 	}
+// 	q.After(values...)
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("squiggle: invalid cursor: %v", err)
+	}
+
+	var values []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("squiggle: invalid cursor: %v", err)
+	}
+
+	for i, value := range values {
+		number, ok := value.(json.Number)
+		if !ok {
+			continue
+		}
+		if n, err := number.Int64(); err == nil {
+			values[i] = n
+		} else if f, err := number.Float64(); err == nil {
+			values[i] = f
+		}
+	}
+
+	return values, nil
+}
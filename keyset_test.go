@@ -0,0 +1,57 @@
+package squiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_After(t *testing.T) {
+	q1 := Select().AddFrom("posts").
+		AddOrdering(Ordering{Field: "created_at", Desc: true}, "id").
+		After("2015-01-01", 10).
+		Limit(20)
+
+	sql, args := q1.ToSQL()
+	expected := "SELECT * FROM posts WHERE created_at < ? OR (created_at = ? AND (id > ? OR id IS NULL)) ORDER BY created_at DESC, id ASC LIMIT 20"
+	if sql != expected {
+		t.Errorf("After() returned `%s` expected `%s`", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"2015-01-01", "2015-01-01", 10}) {
+		t.Errorf("After() returned unexpected args %v", args)
+	}
+
+	q2 := Select().AddFrom("posts").
+		Where("is_draft = false").
+		AddOrdering(Ordering{Table: "p", Field: "score", Nulls: "first"}, Ordering{Field: "id", Nulls: "first"}).
+		After(nil, 10)
+
+	expected = "SELECT * FROM posts WHERE (is_draft = false) AND (p.score IS NOT NULL OR (p.score IS NULL AND id > ?)) ORDER BY p.score ASC NULLS FIRST, id ASC NULLS FIRST"
+	if str := q2.String(); str != expected {
+		t.Errorf("After() returned `%s` expected `%s`", str, expected)
+	}
+
+	q3 := Select().AddOrdering("score").After(nil)
+	if str := q3.String(); str != "SELECT * WHERE 1 = 0 ORDER BY score ASC" {
+		t.Errorf("After() returned `%s` for a cursor at the end of the ordering", str)
+	}
+}
+
+func Test_Cursor(t *testing.T) {
+	cursor, err := EncodeCursor("2015-01-01", 10, 1.5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := DecodeCursor(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{"2015-01-01", int64(10), 1.5, nil}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("DecodeCursor() returned %v expected %v", values, expected)
+	}
+
+	if _, err := DecodeCursor("not a cursor!"); err == nil {
+		t.Error("DecodeCursor() should return an error for an invalid cursor")
+	}
+}
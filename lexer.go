@@ -0,0 +1,195 @@
+package squiggle
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenQuoted
+	tokenString
+	tokenNumber
+	tokenPlaceholder
+	tokenSymbol
+)
+
+// a token of SQL.  start and end are byte offsets into the source, for
+// quoted identifiers text is the identifier without its quotes.
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+}
+
+// reports whether the token is the keyword, keywords are case insensitive
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// reports whether the token is the symbol
+func (t token) isSymbol(symbol string) bool {
+	return t.kind == tokenSymbol && t.text == symbol
+}
+
+// An error parsing SQL with the position it occurred at.  Offset is a byte
+// offset into the SQL, Line and Column start at 1.
+type ParseError struct {
+	Offset  int
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("squiggle: %s at line %d, column %d", e.Message, e.Line, e.Column)
+}
+
+// creates a ParseError for a byte offset into the source
+func newParseError(src string, offset int, format string, args ...interface{}) *ParseError {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	line := 1 + strings.Count(src[:offset], "\n")
+	lineStart := strings.LastIndex(src[:offset], "\n") + 1
+	return &ParseError{
+		Offset:  offset,
+		Line:    line,
+		Column:  utf8.RuneCountInString(src[lineStart:offset]) + 1,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+var twoCharSymbols = []string{"<=", ">=", "<>", "!=", "||", "::"}
+
+// splits SQL into tokens.  Quoted identifiers are recognized using the
+// identifier quotes of the dialect, a doubled right quote inside a quoted
+// identifier is an escaped quote.  MySQL also escapes characters of strings
+// with backslashes.  The last token is always tokenEOF.
+func lex(src string, dialect Dialect) ([]token, error) {
+	var tokens []token
+	leftQuote, rightQuote := dialect.IdentifierLeftQuote, dialect.IdentifierRightQuote
+
+	i := 0
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, newParseError(src, start, "unterminated comment")
+			}
+			i += end + 4
+		case leftQuote != "" && strings.HasPrefix(src[i:], leftQuote):
+			name, end, ok := scanQuoted(src, i+len(leftQuote), rightQuote, false)
+			if !ok {
+				return nil, newParseError(src, start, "unterminated quoted identifier")
+			}
+			tokens = append(tokens, token{kind: tokenQuoted, text: name, start: start, end: end})
+			i = end
+		case r == '\'':
+			_, end, ok := scanQuoted(src, i+1, "'", dialect.Name == MySQL.Name)
+			if !ok {
+				return nil, newParseError(src, start, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: src[start:end], start: start, end: end})
+			i = end
+		case r >= '0' && r <= '9':
+			i = scanNumber(src, i)
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], start: start, end: i})
+		case r == '?':
+			i++
+			tokens = append(tokens, token{kind: tokenPlaceholder, text: "?", start: start, end: i})
+		case (r == '$' || r == '@' || r == ':') && i+1 < len(src) && isWordRune(src[i+1:]) && !strings.HasPrefix(src[i:], "::"):
+			i = scanWord(src, i+1)
+			tokens = append(tokens, token{kind: tokenPlaceholder, text: src[start:i], start: start, end: i})
+		case r == '_' || unicode.IsLetter(r):
+			i = scanWord(src, i)
+			tokens = append(tokens, token{kind: tokenWord, text: src[start:i], start: start, end: i})
+		default:
+			symbol := string(r)
+			for _, s := range twoCharSymbols {
+				if strings.HasPrefix(src[i:], s) {
+					symbol = s
+				}
+			}
+			i += len(symbol)
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol, start: start, end: i})
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, start: len(src), end: len(src)})
+	return tokens, nil
+}
+
+// scans a quoted string starting after its opening quote.  It returns the
+// unescaped contents and the offset after the closing quote.  With backslash
+// a backslash escapes the character following it.
+func scanQuoted(src string, i int, quote string, backslash bool) (string, int, bool) {
+	var contents strings.Builder
+	for i < len(src) {
+		if backslash && src[i] == '\\' && i+1 < len(src) {
+			contents.WriteByte(src[i+1])
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(src[i:], quote) {
+			if strings.HasPrefix(src[i+len(quote):], quote) {
+				contents.WriteString(quote)
+				i += 2 * len(quote)
+				continue
+			}
+			return contents.String(), i + len(quote), true
+		}
+		contents.WriteByte(src[i])
+		i++
+	}
+	return "", i, false
+}
+
+func scanNumber(src string, i int) int {
+	for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+		i++
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if j < len(src) && (src[j] == '+' || src[j] == '-') {
+			j++
+		}
+		if j < len(src) && src[j] >= '0' && src[j] <= '9' {
+			i = j
+			for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func scanWord(src string, i int) int {
+	for i < len(src) && isWordRune(src[i:]) {
+		_, size := utf8.DecodeRuneInString(src[i:])
+		i += size
+	}
+	return i
+}
+
+// reports whether the string starts with a rune that can be part of a word
+func isWordRune(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
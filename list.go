@@ -0,0 +1,293 @@
+package squiggle
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The type values of a filter are converted to
+type ValueType int
+
+const (
+	StringValue ValueType = iota
+	IntValue
+	FloatValue
+	BoolValue
+	// times in RFC 3339 format
+	TimeValue
+)
+
+// A column of a ListSchema
+type ListColumn struct {
+	// the column in SQL, possibly a dotted name, the parameter name when empty
+	Column   string
+	Sortable bool
+	// the filter operators allowed for the column: eq, ne, lt, lte, gt, gte,
+	// in, nin, like and null.  Columns without operators can't be filtered.
+	Operators []string
+	Type      ValueType
+}
+
+// A ListSchema declares the columns list endpoints may sort and filter by,
+// keyed by the name used in request parameters.  Only declared columns end
+// up in SQL, anything else is reported as a ValidationError.
+//
+// 	schema := squiggle.ListSchema{
+// 		"created_at": {Sortable: true, Operators: []string{"gte", "lt"}, Type: squiggle.TimeValue},
+// 		"name":       {Sortable: true},
+// 		"status":     {Column: "u.status", Operators: []string{"eq", "in"}},
+// 	}
+type ListSchema map[string]ListColumn
+
+// A ValidationError describes a request parameter that was rejected
+type ValidationError struct {
+	Param   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("squiggle: %s: %s", e.Param, e.Message)
+}
+
+// ValidationErrors are all the problems found with the request parameters
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// returns the column of a request parameter name
+func (s ListSchema) column(name string) string {
+	if column := s[name].Column; column != "" {
+		return column
+	}
+	return name
+}
+
+// Turns a sort parameter such as "-created_at,name" into orderings.  Names
+// prefixed with - sort descending, names without a prefix or prefixed with
+// + sort ascending.
+//
+// 	schema.Sort("-created_at,name")
+// 	// => []squiggle.Ordering{{Field: "created_at", Desc: true}, {Field: "name"}}
+func (s ListSchema) Sort(param string) ([]Ordering, error) {
+	var orderings []Ordering
+	var errs ValidationErrors
+
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := false
+		if strings.HasPrefix(name, "-") {
+			desc = true
+			name = name[1:]
+		} else if strings.HasPrefix(name, "+") {
+			name = name[1:]
+		}
+
+		if column, ok := s[name]; !ok || !column.Sortable {
+			errs = append(errs, ValidationError{Param: "sort", Message: fmt.Sprintf("can't sort by %q", name)})
+			continue
+		}
+		orderings = append(orderings, Ordering{Field: s.column(name), Desc: desc})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return orderings, nil
+}
+
+// Turns filter parameters such as filter[status]=active and
+// filter[age][gte]=18 into criteria.  A filter without an operator uses eq,
+// the values of in and nin are separated by commas and null takes true or
+// false.  Parameters not starting with filter are ignored.
+//
+// 	schema.Filter(url.Values{"filter[status][in]": {"active,banned"}, "filter[created_at][gte]": {"2020-01-01T00:00:00Z"}})
+// 	// => created_at >= ? AND u.status IN (?, ?)
+func (s ListSchema) Filter(values url.Values) (Criteria, error) {
+	var params []string
+	for param := range values {
+		if strings.HasPrefix(param, "filter[") {
+			params = append(params, param)
+		}
+	}
+	sort.Strings(params)
+
+	var predicates []interface{}
+	var errs ValidationErrors
+	for _, param := range params {
+		name, op, ok := parseFilterParam(param)
+		if !ok {
+			errs = append(errs, ValidationError{Param: param, Message: "malformed filter"})
+			continue
+		}
+		column, ok := s[name]
+		if !ok {
+			errs = append(errs, ValidationError{Param: param, Message: fmt.Sprintf("can't filter by %q", name)})
+			continue
+		}
+		if !matches(column.Operators, op) {
+			errs = append(errs, ValidationError{Param: param, Message: fmt.Sprintf("operator %q is not allowed", op)})
+			continue
+		}
+
+		for _, value := range values[param] {
+			p, err := column.predicate(s.column(name), op, value)
+			if err != nil {
+				errs = append(errs, ValidationError{Param: param, Message: err.Error()})
+				continue
+			}
+			predicates = append(predicates, p)
+		}
+	}
+
+	if len(errs) > 0 {
+		return Criteria{}, errs
+	}
+	if len(predicates) == 0 {
+		return Criteria{}, nil
+	}
+	return And(predicates...), nil
+}
+
+// Adds the sorting and filtering of request parameters to a query.  The sort
+// parameter replaces the orderings of the query, filters are added to its
+// where criteria with AND logic.  The query is not changed when there's a
+// validation error.
+//
+// 	q, err := schema.Apply(squiggle.Select().AddFrom("users"), r.URL.Query())
+func (s ListSchema) Apply(q *Query, values url.Values) (*Query, error) {
+	var errs ValidationErrors
+
+	var orderings []Ordering
+	if param := values.Get("sort"); param != "" {
+		var err error
+		if orderings, err = s.Sort(param); err != nil {
+			errs = append(errs, err.(ValidationErrors)...)
+		}
+	}
+	criteria, err := s.Filter(values)
+	if err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+	if len(errs) > 0 {
+		return q, errs
+	}
+
+	if len(orderings) > 0 {
+		q = q.ClearOrderings()
+		for _, ordering := range orderings {
+			q = q.AddOrdering(ordering)
+		}
+	}
+	if len(criteria.expressions) > 0 {
+		q = q.AndWhere(criteria)
+	}
+
+	return q, nil
+}
+
+// splits filter[name] and filter[name][op] into the name and operator
+func parseFilterParam(param string) (string, string, bool) {
+	rest := strings.TrimPrefix(param, "filter[")
+	end := strings.Index(rest, "]")
+	if end <= 0 {
+		return "", "", false
+	}
+	name, rest := rest[:end], rest[end+1:]
+	if rest == "" {
+		return name, "eq", true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", false
+	}
+	return name, rest[1 : len(rest)-1], true
+}
+
+// returns the predicate of a filter
+func (c ListColumn) predicate(column, op, value string) (Predicate, error) {
+	switch op {
+	case "null":
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return Predicate{}, fmt.Errorf("%q is not true or false", value)
+		}
+		if isNull {
+			return IsNull(column), nil
+		}
+		return IsNotNull(column), nil
+	case "in", "nin":
+		var list []interface{}
+		for _, item := range strings.Split(value, ",") {
+			v, err := c.Type.convert(item)
+			if err != nil {
+				return Predicate{}, err
+			}
+			list = append(list, v)
+		}
+		if op == "in" {
+			return In(column, list), nil
+		}
+		return NotIn(column, list), nil
+	}
+
+	v, err := c.Type.convert(value)
+	if err != nil {
+		return Predicate{}, err
+	}
+	switch op {
+	case "eq":
+		return Eq(column, v), nil
+	case "ne":
+		return NotEq(column, v), nil
+	case "lt":
+		return Lt(column, v), nil
+	case "lte":
+		return Lte(column, v), nil
+	case "gt":
+		return Gt(column, v), nil
+	case "gte":
+		return Gte(column, v), nil
+	case "like":
+		return Like(column, value), nil
+	}
+	return Predicate{}, fmt.Errorf("unknown operator %q", op)
+}
+
+// converts the value of a filter to the type
+func (t ValueType) convert(value string) (interface{}, error) {
+	switch t {
+	case IntValue:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v, nil
+		}
+		return nil, fmt.Errorf("%q is not an integer", value)
+	case FloatValue:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v, nil
+		}
+		return nil, fmt.Errorf("%q is not a number", value)
+	case BoolValue:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v, nil
+		}
+		return nil, fmt.Errorf("%q is not true or false", value)
+	case TimeValue:
+		if v, err := time.Parse(time.RFC3339, value); err == nil {
+			return v, nil
+		}
+		return nil, fmt.Errorf("%q is not an RFC 3339 time", value)
+	}
+	return value, nil
+}
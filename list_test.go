@@ -0,0 +1,102 @@
+package squiggle
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testListSchema = ListSchema{
+	"created_at": {Sortable: true, Operators: []string{"gte", "lt"}, Type: TimeValue},
+	"name":       {Sortable: true, Operators: []string{"eq", "like"}},
+	"status":     {Column: "u.status", Operators: []string{"eq", "in", "nin"}},
+	"age":        {Operators: []string{"gt", "null"}, Type: IntValue},
+}
+
+func Test_ListSchemaSort(t *testing.T) {
+	orderings, err := testListSchema.Sort("-created_at, +name")
+	if err != nil {
+		t.Fatalf("Sort() returned error `%s`", err)
+	}
+	expected := []Ordering{{Field: "created_at", Desc: true}, {Field: "name"}}
+	if !reflect.DeepEqual(orderings, expected) {
+		t.Errorf("Sort() returned %v expected %v", orderings, expected)
+	}
+
+	_, err = testListSchema.Sort("-status,password")
+	expectedErrors := ValidationErrors{
+		{Param: "sort", Message: `can't sort by "status"`},
+		{Param: "sort", Message: `can't sort by "password"`},
+	}
+	if !reflect.DeepEqual(err, expectedErrors) {
+		t.Errorf("Sort() returned error %v expected %v", err, expectedErrors)
+	}
+}
+
+func Test_ListSchemaFilter(t *testing.T) {
+	tests := []struct {
+		values   url.Values
+		expected string
+		args     []interface{}
+	}{
+		{url.Values{"filter[status]": {"active"}, "page": {"2"}}, "u.status = ?", []interface{}{"active"}},
+		{url.Values{"filter[status][in]": {"active,banned"}, "filter[age][gt]": {"18"}}, "age > ? AND u.status IN (?, ?)", []interface{}{int64(18), "active", "banned"}},
+		{url.Values{"filter[age][null]": {"true"}, "filter[name][like]": {"bo%"}}, "age IS NULL AND name LIKE ?", []interface{}{"bo%"}},
+		{url.Values{"filter[created_at][gte]": {"2020-01-02T00:00:00Z"}}, "created_at >= ?", []interface{}{time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		{url.Values{}, "", nil},
+	}
+	for _, test := range tests {
+		criteria, err := testListSchema.Filter(test.values)
+		if err != nil {
+			t.Errorf("Filter() returned error `%s`", err)
+			continue
+		}
+		sql, args := criteria.ToSQL()
+		if sql != test.expected {
+			t.Errorf("Filter() returned `%s` expected `%s`", sql, test.expected)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Filter() returned args %v expected %v", args, test.args)
+		}
+	}
+
+	_, err := testListSchema.Filter(url.Values{
+		"filter[password]": {"x"},
+		"filter[age][eq]":  {"18"},
+		"filter[age][gt]":  {"old"},
+		"filter[status":    {"active"},
+		"filter[status][]": {"active"},
+	})
+	expectedErrors := ValidationErrors{
+		{Param: "filter[age][eq]", Message: `operator "eq" is not allowed`},
+		{Param: "filter[age][gt]", Message: `"old" is not an integer`},
+		{Param: "filter[password]", Message: `can't filter by "password"`},
+		{Param: "filter[status", Message: "malformed filter"},
+		{Param: "filter[status][]", Message: "malformed filter"},
+	}
+	if !reflect.DeepEqual(err, expectedErrors) {
+		t.Errorf("Filter() returned error %v expected %v", err, expectedErrors)
+	}
+}
+
+func Test_ListSchemaApply(t *testing.T) {
+	q1 := Select().AddFrom(From{Table: "users", Alias: "u"}).Where("u.active = true").AddOrdering("id")
+
+	q2, err := testListSchema.Apply(q1.Clone(), url.Values{"sort": {"-created_at"}, "filter[status]": {"active"}})
+	if err != nil {
+		t.Fatalf("Apply() returned error `%s`", err)
+	}
+	expected := "SELECT * FROM users u WHERE (u.active = true) AND (u.status = ?) ORDER BY created_at DESC"
+	if str := q2.String(); str != expected {
+		t.Errorf("Apply() returned `%s` expected `%s`", str, expected)
+	}
+
+	q3, err := testListSchema.Apply(q1, url.Values{"sort": {"password"}, "filter[age]": {"18"}})
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 2 {
+		t.Errorf("Apply() returned unexpected error %v", err)
+	}
+	if q3 != q1 || len(q1.orderings) != 1 {
+		t.Error("Apply() changed the query despite validation errors")
+	}
+}
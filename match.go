@@ -0,0 +1,435 @@
+package squiggle
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the result of evaluating a condition with SQL's three-valued logic
+type truth int
+
+const (
+	unknown truth = iota
+	isFalse
+	isTrue
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return isTrue
+	}
+	return isFalse
+}
+
+func (t truth) not() truth {
+	switch t {
+	case isTrue:
+		return isFalse
+	case isFalse:
+		return isTrue
+	}
+	return unknown
+}
+
+// Evaluates the criteria against a record the way a database would, so the
+// same filter can be applied to rows in SQL and to records held in memory.
+// The record is a map[string]interface{} or a struct or pointer to a struct
+// whose columns are derived from `db` tags like Values() does.  Columns are
+// looked up by their table qualified name first, then by their name.
+//
+// Comparisons with NULL are unknown and NOT unknown stays unknown, so like
+// in SQL a record only matches when the criteria are true.  Predicates,
+// nested criteria, Not() and binary operators over columns, literals and
+// params are supported, criteria strings and other SQL can't be evaluated
+// and return an error.  Empty criteria match every record like a query
+// without WHERE.
+//
+// 	squiggle.And(squiggle.Eq("status", "active"), squiggle.Like("name", "b%")).
+// 		Match(map[string]interface{}{"status": "active", "name": "bob"})
+// 	// => true, nil
+func (c Criteria) Match(record interface{}) (bool, error) {
+	lookup, err := recordLookup(record)
+	if err != nil {
+		return false, err
+	}
+	t, err := evalCondition(c, lookup)
+	return t == isTrue, err
+}
+
+// looks up the value of a column in a record
+type lookupFunc func(table, name string) (interface{}, error)
+
+// returns the lookup of a map or struct record
+func recordLookup(record interface{}) (lookupFunc, error) {
+	if m, ok := record.(map[string]interface{}); ok {
+		return func(table, name string) (interface{}, error) {
+			if table != "" {
+				if v, ok := m[table+"."+name]; ok {
+					return v, nil
+				}
+			}
+			if v, ok := m[name]; ok {
+				return v, nil
+			}
+			return nil, fmt.Errorf("squiggle: record has no column %q", name)
+		}, nil
+	}
+
+	rv, ok := structValue(record)
+	if !ok {
+		return nil, fmt.Errorf("squiggle: unexpected type %T used in Match()", record)
+	}
+	columns := getStructInfo(rv.Type()).columns
+	return func(table, name string) (interface{}, error) {
+		for _, column := range columns {
+			if column.name != name {
+				continue
+			}
+			if fv, ok := column.value(rv); ok {
+				return fv.Interface(), nil
+			}
+			return nil, nil
+		}
+		return nil, fmt.Errorf("squiggle: record has no column %q", name)
+	}, nil
+}
+
+// evaluates a condition: criteria, a predicate or a boolean expression
+func evalCondition(node interface{}, lookup lookupFunc) (truth, error) {
+	switch n := node.(type) {
+	case Criteria:
+		// empty criteria render nothing, they match everything and nested
+		// ones are skipped
+		result, empty := truthOf(n.and), true
+		for _, expression := range n.expressions {
+			if c, ok := expression.(Criteria); ok && len(c.expressions) == 0 {
+				continue
+			}
+			empty = false
+			t, err := evalCondition(expression, lookup)
+			if err != nil {
+				return unknown, err
+			}
+			if n.and {
+				result = and(result, t)
+			} else {
+				result = or(result, t)
+			}
+		}
+		if empty {
+			return isTrue, nil
+		}
+		return result, nil
+	case Predicate:
+		return evalPredicate(n, lookup)
+	case Unary:
+		if strings.ToUpper(n.Op) != "NOT" {
+			break
+		}
+		t, err := evalCondition(n.Operand, lookup)
+		return t.not(), err
+	case Binary:
+		return evalBinary(n, lookup)
+	case string:
+		return unknown, fmt.Errorf("squiggle: can't evaluate the criteria string %q", n)
+	}
+	return unknown, fmt.Errorf("squiggle: can't evaluate %T", node)
+}
+
+func and(a, b truth) truth {
+	if a == isFalse || b == isFalse {
+		return isFalse
+	}
+	if a == unknown || b == unknown {
+		return unknown
+	}
+	return isTrue
+}
+
+func or(a, b truth) truth {
+	if a == isTrue || b == isTrue {
+		return isTrue
+	}
+	if a == unknown || b == unknown {
+		return unknown
+	}
+	return isFalse
+}
+
+// evaluates a predicate
+func evalPredicate(p Predicate, lookup lookupFunc) (truth, error) {
+	value, err := lookup(p.Table, p.Field)
+	if err != nil {
+		return unknown, err
+	}
+	return compare(value, strings.ToUpper(p.Op), p.Value)
+}
+
+// evaluates a binary operator
+func evalBinary(b Binary, lookup lookupFunc) (truth, error) {
+	op := strings.ToUpper(b.Op)
+	if op == "AND" || op == "OR" {
+		left, err := evalCondition(b.Left, lookup)
+		if err != nil {
+			return unknown, err
+		}
+		right, err := evalCondition(b.Right, lookup)
+		if err != nil {
+			return unknown, err
+		}
+		if op == "AND" {
+			return and(left, right), nil
+		}
+		return or(left, right), nil
+	}
+
+	left, err := evalValue(b.Left, lookup)
+	if err != nil {
+		return unknown, err
+	}
+	right, err := evalValue(b.Right, lookup)
+	if err != nil {
+		return unknown, err
+	}
+	return compare(left, op, right)
+}
+
+// evaluates an expression that is a value
+func evalValue(e Expression, lookup lookupFunc) (interface{}, error) {
+	switch v := e.(type) {
+	case Column:
+		return lookup(v.Table, v.Name)
+	case Literal:
+		return v.Value, nil
+	case Param:
+		return v.Value, nil
+	case List:
+		var values []interface{}
+		for _, item := range v {
+			value, err := evalValue(item, lookup)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("squiggle: can't evaluate %T", e)
+}
+
+// compares a value to another with an operator
+func compare(left interface{}, op string, right interface{}) (truth, error) {
+	left, err := matchValue(left)
+	if err != nil {
+		return unknown, err
+	}
+
+	switch op {
+	case "IS NULL":
+		return truthOf(left == nil), nil
+	case "IS NOT NULL":
+		return truthOf(left != nil), nil
+	case "IN", "NOT IN":
+		if !isList(right) {
+			return unknown, fmt.Errorf("squiggle: %s needs a list of values", op)
+		}
+		result := isFalse
+		for _, item := range listValues(right) {
+			t, err := compare(left, "=", item)
+			if err != nil {
+				return unknown, err
+			}
+			result = or(result, t)
+		}
+		if op == "NOT IN" {
+			return result.not(), nil
+		}
+		return result, nil
+	}
+
+	right, err = matchValue(right)
+	if err != nil {
+		return unknown, err
+	}
+	if left == nil || right == nil {
+		return unknown, nil
+	}
+
+	switch op {
+	case "LIKE", "NOT LIKE":
+		s, ok1 := left.(string)
+		pattern, ok2 := right.(string)
+		if !ok1 || !ok2 {
+			return unknown, fmt.Errorf("squiggle: can't match %T LIKE %T", left, right)
+		}
+		t := truthOf(likePattern(pattern).MatchString(s))
+		if op == "NOT LIKE" {
+			return t.not(), nil
+		}
+		return t, nil
+	}
+
+	c, err := compareValues(left, right)
+	if err != nil {
+		return unknown, err
+	}
+	switch op {
+	case "=":
+		return truthOf(c == 0), nil
+	case "<>", "!=":
+		return truthOf(c != 0), nil
+	case "<":
+		return truthOf(c < 0), nil
+	case "<=":
+		return truthOf(c <= 0), nil
+	case ">":
+		return truthOf(c > 0), nil
+	case ">=":
+		return truthOf(c >= 0), nil
+	}
+	return unknown, fmt.Errorf("squiggle: can't evaluate the operator %s", op)
+}
+
+// returns a value the way the database would see it: nil pointers are NULL,
+// other pointers are dereferenced, driver.Valuers such as sql.NullString are
+// replaced by their value and numbers are widened to int64, uint64 or
+// float64
+func matchValue(value interface{}) (interface{}, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if isNil(value) {
+			return nil, nil
+		}
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	}
+	return rv.Interface(), nil
+}
+
+// compares two non-NULL values returning -1, 0 or 1
+func compareValues(left, right interface{}) (int, error) {
+	switch l := left.(type) {
+	case int64, uint64, float64:
+		if c, ok := compareNumbers(left, right); ok {
+			return c, nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	case bool:
+		if r, ok := right.(bool); ok {
+			return sign(!l && r, l && !r), nil
+		}
+	case time.Time:
+		if r, ok := right.(time.Time); ok {
+			return sign(l.Before(r), l.After(r)), nil
+		}
+	}
+	return 0, fmt.Errorf("squiggle: can't compare %T to %T", left, right)
+}
+
+// compares two numbers.  Integers of the same signedness are compared
+// exactly, anything else as float64.
+func compareNumbers(left, right interface{}) (int, bool) {
+	var l, r float64
+	switch a := left.(type) {
+	case int64:
+		if b, ok := right.(int64); ok {
+			return sign(a < b, a > b), true
+		}
+		l = float64(a)
+	case uint64:
+		if b, ok := right.(uint64); ok {
+			return sign(a < b, a > b), true
+		}
+		l = float64(a)
+	case float64:
+		l = a
+	}
+	switch b := right.(type) {
+	case int64:
+		r = float64(b)
+	case uint64:
+		r = float64(b)
+	case float64:
+		r = b
+	default:
+		return 0, false
+	}
+	return sign(l < r, l > r), true
+}
+
+// returns -1 when less, 1 when greater and 0 otherwise
+func sign(less, greater bool) int {
+	if less {
+		return -1
+	} else if greater {
+		return 1
+	}
+	return 0
+}
+
+// the compiled regular expressions of LIKE patterns by pattern
+var likePatterns sync.Map
+
+// returns the regular expression of a LIKE pattern.  % matches any number
+// of characters, _ matches one and \ escapes.  Compiled patterns are cached.
+func likePattern(pattern string) *regexp.Regexp {
+	if re, ok := likePatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	var re strings.Builder
+	re.WriteString("(?s)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			re.WriteString(".*")
+		case r == '_':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	compiled := regexp.MustCompile(re.String())
+	likePatterns.Store(pattern, compiled)
+	return compiled
+}
@@ -0,0 +1,105 @@
+package squiggle
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func Test_CriteriaMatch(t *testing.T) {
+	created := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	record := map[string]interface{}{
+		"status":     "active",
+		"name":       "bob_smith",
+		"age":        30,
+		"score":      9.5,
+		"admin":      false,
+		"deleted_at": nil,
+		"created_at": created,
+		"nickname":   sql.NullString{},
+		"u.role":     "owner",
+		"role":       "guest",
+	}
+
+	tests := []struct {
+		c        Criteria
+		expected bool
+	}{
+		{And(Eq("status", "active"), Gt("age", int8(18))), true},
+		{And(Eq("status", "active"), Lt("age", 18)), false},
+		{Or(Eq("status", "banned"), Gte("score", 9)), true},
+		{And(Lte("age", 30.0), NotEq("admin", true)), true},
+		{And(Like("name", "bob\\_%")), true},
+		{And(Like("name", "b_b%"), NotLike("name", "%jones")), true},
+		{And(In("age", []int{20, 30})), true},
+		{And(NotIn("age", []int{20, 30})), false},
+		{And(IsNull("deleted_at"), IsNull("nickname")), true},
+		{And(IsNotNull("deleted_at")), false},
+		{And(Gt("created_at", created.Add(-time.Hour))), true},
+		{And(Predicate{Table: "u", Field: "role", Op: "=", Value: "owner"}), true},
+		{And(Eq("role", "guest")), true},
+		{And(Op(Col("age"), ">", Lit(20)), Op(Col("status"), "=", Arg("active"))), true},
+		{And(Not(Eq("status", "active"))), false},
+
+		// comparisons with NULL are unknown and never match
+		{And(Predicate{Field: "deleted_at", Op: "=", Value: created}), false},
+		{And(Not(Predicate{Field: "deleted_at", Op: "=", Value: created})), false},
+		{Or(Predicate{Field: "deleted_at", Op: "<", Value: created}, Eq("status", "active")), true},
+		{And(NotIn("age", []interface{}{20, nil})), false},
+		{And(Not(In("age", []interface{}{20, nil}))), false},
+		{And(In("age", []interface{}{30, nil})), true},
+
+		// empty criteria don't filter, also when nested
+		{Criteria{}, true},
+		{Or(), true},
+		{And(Eq("status", "active"), Or()), true},
+		{Or(Eq("status", "banned"), And()), false},
+	}
+	for _, test := range tests {
+		matched, err := test.c.Match(record)
+		if err != nil {
+			t.Errorf("Match() returned error `%s` for %s", err, test.c)
+			continue
+		}
+		if matched != test.expected {
+			t.Errorf("Match() returned %v expected %v for %s", matched, test.expected, test.c)
+		}
+	}
+}
+
+func Test_CriteriaMatchStruct(t *testing.T) {
+	user := testUser{ID: 1, Username: "bob", Nickname: "bobby"}
+
+	matched, err := And(Eq("username", "bob"), Eq("Nickname", "bobby"), NotIn("id", []int{2, 3})).Match(&user)
+	if err != nil || !matched {
+		t.Errorf("Match() returned %v, %v for a struct", matched, err)
+	}
+}
+
+func Test_CriteriaMatchErrors(t *testing.T) {
+	record := map[string]interface{}{"status": "active", "age": 30}
+	tests := []Criteria{
+		And("status = 'active'"),
+		And(Eq("missing", 1)),
+		And(Gt("status", 1)),
+		And(Op(Fn("LOWER", Col("status")), "=", Lit("active"))),
+		And(Like("age", "3%")),
+	}
+	for _, c := range tests {
+		if _, err := c.Match(record); err == nil {
+			t.Errorf("Match() should return an error for %s", c)
+		}
+	}
+	if _, err := And(Eq("a", 1)).Match(42); err == nil {
+		t.Error("Match() should return an error for a record that isn't a map or struct")
+	}
+}
+
+func Test_likePattern(t *testing.T) {
+	if likePattern("bob\\_%") != likePattern("bob\\_%") {
+		t.Error("likePattern() compiled a cached pattern again")
+	}
+	if re := likePattern("a_c%"); !re.MatchString("abcdef") || re.MatchString("acdef") {
+		t.Errorf("likePattern() returned unexpected regular expression `%s`", re)
+	}
+}
@@ -0,0 +1,252 @@
+package squiggle
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A Middleware rewrites queries when they are rendered.  Rewrite receives a
+// copy of the query that it may modify and returns the query to render.
+// Middleware is applied to subqueries as well.
+type Middleware struct {
+	Name    string
+	Rewrite func(q *Query) *Query
+}
+
+var (
+	middlewareMutex sync.RWMutex
+	middleware      []Middleware
+)
+
+// Registers middleware applied to every query rendered by the program.
+// Queries can opt out with SkipMiddleware().
+//
+// 	squiggle.Use(squiggle.TenantScope("tenant_id", "users", "orders"))
+func Use(m ...Middleware) {
+	middlewareMutex.Lock()
+	defer middlewareMutex.Unlock()
+	middleware = append(middleware, m...)
+}
+
+// Removes all middleware registered with Use()
+func ResetMiddleware() {
+	middlewareMutex.Lock()
+	defer middlewareMutex.Unlock()
+	middleware = nil
+}
+
+// Adds middleware applied only to this query, after the middleware
+// registered with Use()
+func (q *Query) Use(m ...Middleware) *Query {
+	q = q.builder()
+	q.middleware = append(append([]Middleware(nil), q.middleware...), m...)
+
+	return q
+}
+
+// Opts a query out of middleware by name.  Without arguments the query
+// opts out of all middleware.
+//
+// 	squiggle.Select().AddFrom("tenants").SkipMiddleware("tenant")
+func (q *Query) SkipMiddleware(names ...string) *Query {
+	q = q.builder()
+	if len(names) == 0 {
+		q.skipAllMiddleware = true
+	}
+	q.skipMiddleware = append(append([]string(nil), q.skipMiddleware...), names...)
+
+	return q
+}
+
+// Returns a copy of the query with a value attached to it.  Values are used
+// to pass information such as the current tenant to middleware.
+func (q *Query) WithValue(key, value interface{}) *Query {
+	q = q.builder()
+	values := map[interface{}]interface{}{}
+	for k, v := range q.values {
+		values[k] = v
+	}
+	values[key] = value
+	q.values = values
+
+	return q
+}
+
+// Returns the value attached to the query for a key or nil
+func (q *Query) Value(key interface{}) interface{} {
+	return q.values[key]
+}
+
+// Returns the type of the query such as "SELECT" or "INSERT"
+func (q *Query) Type() string {
+	return q.queryType
+}
+
+// Returns the tables of the from clause of the query
+func (q *Query) Froms() []From {
+	return append([]From(nil), q.from...)
+}
+
+// Returns the joins of the query
+func (q *Query) Joins() []Join {
+	return append([]Join(nil), q.joins...)
+}
+
+// returns the query with middleware applied.  The query is returned
+// unchanged when there's no middleware to apply.
+func (q *Query) applyMiddleware() *Query {
+	if q.middlewareApplied || q.skipAllMiddleware {
+		return q
+	}
+
+	middlewareMutex.RLock()
+	all := append(append(tableMiddleware(), middleware...), q.middleware...)
+	middlewareMutex.RUnlock()
+
+	rewritten := q
+	for _, m := range all {
+		if matches(q.skipMiddleware, m.Name) {
+			continue
+		}
+		if rewritten == q {
+			rewritten = q.Mutable()
+			rewritten.middlewareApplied = true
+		}
+		rewritten = m.Rewrite(rewritten)
+	}
+	if rewritten != q {
+		rewritten.middlewareApplied = true
+	}
+
+	return rewritten
+}
+
+// applies middleware like applyMiddleware(), returning the error middleware
+// panics with such as a *TenantError instead of panicking
+func (q *Query) tryMiddleware() (rewritten *Query, err error) {
+	defer recoverError(&err)
+	return q.applyMiddleware(), nil
+}
+
+// stores the error of a panic with an error in err, other panics continue
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(error)
+		if !ok {
+			panic(r)
+		}
+		*err = e
+	}
+}
+
+// returns a subquery with the values, middleware opt outs and strict mode
+// of the query it belongs to.  Values of the subquery take precedence.
+func (q *Query) subqueryOf(parent *Query) *Query {
+	if len(parent.values) == 0 && len(parent.skipMiddleware) == 0 && !parent.skipAllMiddleware && !parent.strictIdentifiers {
+		return q
+	}
+
+	c := q.Mutable()
+	for key, value := range parent.values {
+		if _, ok := q.values[key]; !ok {
+			c = c.WithValue(key, value)
+		}
+	}
+	c.skipMiddleware = append(append([]string(nil), q.skipMiddleware...), parent.skipMiddleware...)
+	c.skipAllMiddleware = q.skipAllMiddleware || parent.skipAllMiddleware
+	if parent.strictIdentifiers && !c.strictIdentifiers {
+		c.strictIdentifiers = true
+		c.allowedIdentifiers = parent.allowedIdentifiers
+	}
+
+	return c
+}
+
+// The key of the query value holding the tenant for TenantScope()
+const TenantKey = "tenant"
+
+// The error rendering a query of a tenant scoped table without a tenant
+// panics with, Validate() reports it and a Runner returns it instead.
+// Compiling a query with a tenant that isn't a Slot panics with it too, then
+// Tenant is the tenant.
+type TenantError struct {
+	Table  string
+	Tenant interface{}
+}
+
+func (e *TenantError) Error() string {
+	if e.Tenant != nil {
+		return fmt.Sprintf("squiggle: compiled query of tenant scoped table %s has the tenant %v instead of a slot", e.Table, e.Tenant)
+	}
+	return fmt.Sprintf("squiggle: query of tenant scoped table %s without a tenant", e.Table)
+}
+
+// Sets the tenant of a query for TenantScope()
+func (q *Query) WithTenant(tenant interface{}) *Query {
+	return q.WithValue(TenantKey, tenant)
+}
+
+// Creates middleware named "tenant" for multi-tenant databases.  For every
+// table of the from clause and every join of one of the tables it adds
+// criteria comparing the column to the tenant of the query, set with
+// WithTenant().  Criteria for joins are added to their ON criteria so outer
+// joins keep working, those of CROSS and NATURAL joins to WHERE.  DELETE
+// queries of the tables, including soft deletes, are scoped the same way.
+// Rendering a SELECT or DELETE of one of the tables without a tenant panics
+// with a *TenantError so tenant criteria can't be forgotten, queries that
+// really need all tenants can opt out with SkipMiddleware("tenant").
+// Compiled queries need a Slot as their tenant, see Compile().
+//
+// 	squiggle.Use(squiggle.TenantScope("tenant_id", "users", "orders"))
+// 	squiggle.Select().
+// 		AddFrom(squiggle.From{Table: "users", Alias: "u"}).
+// 		AddJoin(squiggle.Join{Type: "left", Table: "orders", Alias: "o", On: squiggle.And("o.user_id = u.id")}).
+// 		WithTenant(42)
+// 	// => SELECT * FROM users u LEFT JOIN orders o ON (o.user_id = u.id) AND o.tenant_id = ? WHERE u.tenant_id = ?
+func TenantScope(column string, tables ...string) Middleware {
+	tenantOf := func(q *Query, table string) interface{} {
+		tenant := q.Value(TenantKey)
+		if tenant == nil {
+			panic(&TenantError{Table: table})
+		}
+		if _, ok := tenant.(Slot); !ok && q.Compiling() {
+			panic(&TenantError{Table: table, Tenant: tenant})
+		}
+		return tenant
+	}
+	scope := func(q *Query, schema, table, alias string) Predicate {
+		return tablePredicate(schema, table, alias, Eq(column, tenantOf(q, table)))
+	}
+
+	return Middleware{
+		Name: "tenant",
+		Rewrite: func(q *Query) *Query {
+			switch q.queryType {
+			case "DELETE":
+				// DELETE doesn't alias its table, soft deletes become an
+				// UPDATE of the same table with the same criteria
+				for _, from := range q.from {
+					if matches(tables, from.Table) {
+						q.AndWhere(Eq(column, tenantOf(q, from.Table)))
+					}
+				}
+				return q
+			case "SELECT":
+			default:
+				return q
+			}
+			for _, from := range q.from {
+				if from.Subquery == nil && matches(tables, from.Table) {
+					q.AndWhere(scope(q, from.Schema, from.Table, from.Alias))
+				}
+			}
+			for i, join := range q.joins {
+				if !matches(tables, join.Table) {
+					continue
+				}
+				q.addJoinCriteria(i, scope(q, join.Schema, join.Table, join.Alias))
+			}
+			return q
+		},
+	}
+}
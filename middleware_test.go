@@ -0,0 +1,93 @@
+package squiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_TenantScope(t *testing.T) {
+	Use(TenantScope("tenant_id", "users", "orders"))
+	defer ResetMiddleware()
+
+	q1 := Select().
+		AddFrom(From{Table: "users", Alias: "u"}, From{Subquery: Select().AddFrom("orders"), Alias: "o2"}).
+		AddJoin(Join{Type: "left", Table: "orders", Alias: "o", On: And("o.user_id = u.id")}, Join{Type: "inner", Table: "roles", On: And("roles.id = u.role_id")}).
+		Where("u.active = true").
+		WithTenant(42)
+
+	sql, args := q1.ToSQL()
+	expected := "SELECT * FROM users u, (SELECT * FROM orders WHERE orders.tenant_id = ?) o2 LEFT JOIN orders o ON (o.user_id = u.id) AND o.tenant_id = ? INNER JOIN roles ON roles.id = u.role_id WHERE (u.active = true) AND (u.tenant_id = ?)"
+	if sql != expected {
+		t.Errorf("ToSQL() returned `%s` expected `%s`", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{42, 42, 42}) {
+		t.Errorf("ToSQL() returned unexpected args %v", args)
+	}
+	if len(q1.where.expressions) != 1 || len(q1.joins[0].On.expressions) != 1 {
+		t.Error("middleware modified the original query")
+	}
+
+	if str := Select().AddFrom("users").SkipMiddleware("tenant").String(); str != "SELECT * FROM users" {
+		t.Errorf("SkipMiddleware() did not opt out of middleware `%s`", str)
+	}
+
+	q2 := Select().AddFrom("users").AddJoin(Join{Type: "cross", Table: "orders"}, Join{Type: "natural", Table: "orders", Alias: "o"}).WithTenant(42)
+	expected = "SELECT * FROM users CROSS JOIN orders NATURAL JOIN orders o WHERE users.tenant_id = ? AND orders.tenant_id = ? AND o.tenant_id = ?"
+	if str := q2.String(); str != expected {
+		t.Errorf("String() returned `%s` expected `%s`", str, expected)
+	}
+
+	q3 := Select().AddFrom("users").AddJoin(Join{Type: "left", Table: "orders", On: And("orders.user_id = users.id OR orders.shared = true")}).WithTenant(42)
+	expected = "SELECT * FROM users LEFT JOIN orders ON (orders.user_id = users.id OR orders.shared = true) AND orders.tenant_id = ? WHERE users.tenant_id = ?"
+	if str := q3.String(); str != expected {
+		t.Errorf("String() returned `%s` expected `%s`", str, expected)
+	}
+
+	sql, args = Delete("orders").Where("id = 1 OR id = 2").WithTenant(42).ToSQL()
+	expected = "DELETE FROM orders WHERE (id = 1 OR id = 2) AND (tenant_id = ?)"
+	if sql != expected || !reflect.DeepEqual(args, []interface{}{42}) {
+		t.Errorf("ToSQL() returned `%s` %v expected `%s`", sql, args, expected)
+	}
+
+	RegisterTable(Table{Name: "users", SoftDelete: "deleted_at"})
+	defer ResetTables()
+	sql, args = Delete("users").Where(Eq("id", 1)).WithTenant(42).ToSQL()
+	expected = "UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE (id = ?) AND (tenant_id = ?) AND deleted_at IS NULL"
+	if sql != expected || !reflect.DeepEqual(args, []interface{}{1, 42}) {
+		t.Errorf("ToSQL() returned `%s` %v expected `%s`", sql, args, expected)
+	}
+	if _, _, err := Delete("users").Where(Eq("id", 1)).trySQL(); err == nil {
+		t.Error("rendering a tenant scoped DELETE without a tenant should fail with a *TenantError")
+	} else if _, ok := err.(*TenantError); !ok {
+		t.Errorf("rendering a tenant scoped DELETE returned unexpected error %v", err)
+	}
+
+	problems := Select().AddFrom("users").Validate()
+	if len(problems) != 1 || problems[0].Code != "middleware" || problems[0].Message != "squiggle: query of tenant scoped table users without a tenant" {
+		t.Errorf("Validate() returned unexpected problems %v", problems)
+	}
+
+	defer func() {
+		if _, ok := recover().(*TenantError); !ok {
+			t.Error("rendering a tenant scoped query without a tenant should panic with a *TenantError")
+		}
+	}()
+	_ = Select().AddFrom("users").String()
+}
+
+func Test_QueryUse(t *testing.T) {
+	limit := Middleware{Name: "limit", Rewrite: func(q *Query) *Query {
+		if q.limit == 0 || q.limit > 100 {
+			q.Limit(100)
+		}
+		return q
+	}}
+
+	q1 := Select().AddFrom("users").Use(limit)
+	if str := q1.String(); str != "SELECT * FROM users LIMIT 100" {
+		t.Errorf("Use() middleware was not applied `%s`", str)
+	}
+	if str := q1.SkipMiddleware().String(); str != "SELECT * FROM users" {
+		t.Errorf("SkipMiddleware() did not opt out of all middleware `%s`", str)
+	}
+}
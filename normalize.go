@@ -0,0 +1,275 @@
+package squiggle
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Returns the criteria in their simplest form.  Nested criteria with the
+// same logic are merged into their parent, criteria with a single
+// expression are replaced by it, empty criteria are dropped and exact
+// duplicates are removed keeping the first.  The order of the remaining
+// expressions is kept so the same criteria always render the same SQL.
+// Strings with OR outside of parentheses and expressions other than
+// predicates keep the parentheses of their criteria so the criteria mean the
+// same.
+//
+// 	squiggle.And(squiggle.And(squiggle.Eq("a", 1)), squiggle.And(squiggle.Eq("b", 2), squiggle.Or()), squiggle.Eq("a", 1)).Normalize()
+// 	// => a = ? AND b = ?
+func (c Criteria) Normalize() Criteria {
+	if len(c.expressions) == 0 {
+		return c
+	}
+
+	normalized := normalizeNode(c)
+	if criteria, ok := normalized.(Criteria); ok {
+		return criteria
+	}
+	return Criteria{and: true, expressions: []interface{}{normalized}}
+}
+
+// returns a normalized criteria string, criteria or expression.  Criteria
+// with a single predicate or criteria are returned as it.
+func normalizeNode(node interface{}) interface{} {
+	switch n := node.(type) {
+	case Criteria:
+		c := Criteria{and: n.and}
+		for _, expression := range n.expressions {
+			expression = normalizeNode(expression)
+			if nested, ok := expression.(Criteria); ok {
+				if len(nested.expressions) == 0 {
+					continue
+				}
+				if nested.and == n.and && mergeable(nested.expressions...) {
+					for _, e := range nested.expressions {
+						c.expressions = appendUnique(c.expressions, e)
+					}
+					continue
+				}
+			}
+			c.expressions = appendUnique(c.expressions, expression)
+		}
+		if len(c.expressions) == 1 && mergeable(c.expressions[0]) {
+			return c.expressions[0]
+		}
+		return c
+	case Unary:
+		if c, ok := n.Operand.(Criteria); ok {
+			n.Operand = c.Normalize()
+		}
+		return n
+	}
+	return node
+}
+
+// reports whether expressions can lose the parentheses of the criteria
+// they're in: predicates, criteria and NOT, which render their own
+// parentheses when they need them, and strings without OR outside of
+// parentheses, but not other expressions
+func mergeable(expressions ...interface{}) bool {
+	for _, e := range expressions {
+		switch n := e.(type) {
+		case Predicate, Criteria:
+		case string:
+			if hasTopLevelOr(n) {
+				return false
+			}
+		case Unary:
+			if strings.ToUpper(n.Op) != "NOT" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// reports whether SQL has OR, or an operator binding as loosely, outside of
+// parentheses.  SQL that can't be lexed is assumed to.
+func hasTopLevelOr(sql string) bool {
+	tokens, err := lex(sql, Dialect{})
+	if err != nil {
+		return true
+	}
+	depth := 0
+	for _, t := range tokens {
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case depth == 0 && (t.is("OR") || t.is("XOR") || t.isSymbol("||")):
+			return true
+		}
+	}
+	return false
+}
+
+// appends an expression unless an equal one is already there
+func appendUnique(expressions []interface{}, e interface{}) []interface{} {
+	for _, existing := range expressions {
+		if reflect.DeepEqual(existing, e) {
+			return expressions
+		}
+	}
+	return append(expressions, e)
+}
+
+// Returns the criteria in conjunctive normal form: an AND of ORs of
+// predicates, strings and other expressions.  Negations are pushed down to
+// the predicates with De Morgan's laws, which holds with SQL's NULL logic as
+// well.  The criteria can grow exponentially so this is meant for small,
+// user built filters.
+//
+// 	squiggle.Or("a = 1", squiggle.And("b = 2", "c = 3")).CNF()
+// 	// => (a = 1 OR b = 2) AND (a = 1 OR c = 3)
+func (c Criteria) CNF() Criteria {
+	return normalForm(c, true)
+}
+
+// Returns the criteria in disjunctive normal form: an OR of ANDs, see CNF()
+//
+// 	squiggle.And("a = 1", squiggle.Or("b = 2", "c = 3")).DNF()
+// 	// => (a = 1 AND b = 2) OR (a = 1 AND c = 3)
+func (c Criteria) DNF() Criteria {
+	return normalForm(c, false)
+}
+
+// returns criteria in CNF when and is true, otherwise in DNF
+func normalForm(c Criteria, and bool) Criteria {
+	c = c.Normalize()
+	if len(c.expressions) == 0 {
+		return c
+	}
+
+	result := Criteria{and: and}
+	for _, clause := range clauses(c, and) {
+		result.expressions = appendUnique(result.expressions, Criteria{and: !and, expressions: clause})
+	}
+	return result.Normalize()
+}
+
+// returns the clauses of a node in normal form.  With and the node is the
+// AND of the clauses and each clause is the OR of its expressions, without
+// it the other way round.
+func clauses(node interface{}, and bool) [][]interface{} {
+	switch n := node.(type) {
+	case Criteria:
+		if n.and == and {
+			var result [][]interface{}
+			for _, expression := range n.expressions {
+				result = append(result, clauses(expression, and)...)
+			}
+			return result
+		}
+
+		// distribute: pick one clause of every expression
+		result := [][]interface{}{nil}
+		for _, expression := range n.expressions {
+			var combined [][]interface{}
+			for _, clause := range clauses(expression, and) {
+				for _, existing := range result {
+					merged := append([]interface{}(nil), existing...)
+					for _, e := range clause {
+						merged = appendUnique(merged, e)
+					}
+					combined = append(combined, merged)
+				}
+			}
+			result = combined
+		}
+		return result
+	case Unary:
+		if strings.ToUpper(n.Op) == "NOT" {
+			if negated, ok := negate(n.Operand); ok {
+				return clauses(negated, and)
+			}
+		}
+	}
+	return [][]interface{}{{node}}
+}
+
+// the operators of predicates and their negations
+var negatedOps = map[string]string{
+	"=":           "<>",
+	"<>":          "=",
+	"!=":          "=",
+	"<":           ">=",
+	"<=":          ">",
+	">":           "<=",
+	">=":          "<",
+	"IN":          "NOT IN",
+	"NOT IN":      "IN",
+	"LIKE":        "NOT LIKE",
+	"NOT LIKE":    "LIKE",
+	"IS NULL":     "IS NOT NULL",
+	"IS NOT NULL": "IS NULL",
+}
+
+// returns the negation of a node without NOT at the top when there is one
+func negate(node interface{}) (interface{}, bool) {
+	switch n := node.(type) {
+	case Criteria:
+		if len(n.expressions) == 1 {
+			if _, ok := n.expressions[0].(string); ok {
+				// NOT (string) can't be simplified
+				return nil, false
+			}
+		}
+		negated := Criteria{and: !n.and}
+		for _, expression := range n.expressions {
+			if e, ok := negate(expression); ok {
+				negated.expressions = append(negated.expressions, e)
+			} else if e, ok := expression.(Expression); ok {
+				negated.expressions = append(negated.expressions, Not(e))
+			} else {
+				negated.expressions = append(negated.expressions, Not(expression.(string)))
+			}
+		}
+		return negated, true
+	case Predicate:
+		if op, ok := negatedOps[strings.ToUpper(n.Op)]; ok {
+			n.Op = op
+			return n, true
+		}
+	case Unary:
+		if strings.ToUpper(n.Op) == "NOT" {
+			return n.Operand, true
+		}
+	}
+	return nil, false
+}
+
+// Normalizes the where and having criteria of a query and the ON criteria of
+// its joins, see Criteria.Normalize()
+//
+// 	squiggle.Select().AddFrom("users").Where("a = 1").AndWhere("b = 2").AndWhere("a = 1").Normalize()
+// 	// => SELECT * FROM users WHERE a = 1 AND b = 2
+func (q *Query) Normalize() *Query {
+	q = q.builder()
+	q.where = q.where.Normalize()
+	q.having = q.having.Normalize()
+	joins := make([]Join, len(q.joins))
+	for i, join := range q.joins {
+		join.On = join.On.Normalize()
+		joins[i] = join
+	}
+	q.joins = joins
+
+	return q
+}
+
+// Creates middleware named "normalize" that normalizes the criteria of every
+// query, see Query.Normalize().  Register it after middleware adding
+// criteria so their criteria are normalized too.
+//
+// 	squiggle.Use(squiggle.TenantScope("tenant_id", "users"), squiggle.NormalizeCriteria())
+func NormalizeCriteria() Middleware {
+	return Middleware{
+		Name: "normalize",
+		Rewrite: func(q *Query) *Query {
+			return q.Normalize()
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_CriteriaNormalize(t *testing.T) {
+	tests := []struct {
+		c        Criteria
+		expected string
+	}{
+		{And(And(Eq("a", 1)), And(Eq("b", 2), Or()), Eq("a", 1)), "a = ? AND b = ?"},
+		{And(And(And(Eq("a", 1), Eq("b", 2)), Eq("c", 3)), Or(Eq("d", 4), Or(Eq("e", 5), Eq("f", 6)))), "a = ? AND b = ? AND c = ? AND (d = ? OR e = ? OR f = ?)"},
+		{And(And("a = 1"), And("b = 2", Or()), And("a = 1")), "a = 1 AND b = 2"},
+		{And(And("a = 1 OR b = 2"), And("c = 3", Eq("d", 4))), "(a = 1 OR b = 2) AND c = 3 AND d = ?"},
+		{And(Or("a = 1", "b = 2")), "a = 1 OR b = 2"},
+		{Or(Eq("a", 1), Eq("a", 1), Eq("a", 2), In("b", []int{1, 2}), In("b", []int{1, 2})), "a = ? OR a = ? OR b IN (?, ?)"},
+		{And(Not(And(And("a = 1"))), "b = 2"), "NOT (a = 1) AND b = 2"},
+		{And(Or(), And()), ""},
+	}
+	for _, test := range tests {
+		if str := test.c.Normalize().String(); str != test.expected {
+			t.Errorf("Normalize() returned `%s` expected `%s`", str, test.expected)
+		}
+	}
+}
+
+func Test_CriteriaNormalForms(t *testing.T) {
+	tests := []struct {
+		c   Criteria
+		cnf string
+		dnf string
+	}{
+		{Or("a = 1", And("b = 2", "c = 3")), "(a = 1 OR b = 2) AND (a = 1 OR c = 3)", "a = 1 OR (b = 2 AND c = 3)"},
+		{And("a = 1", Or("b = 2", "c = 3")), "a = 1 AND (b = 2 OR c = 3)", "(a = 1 AND b = 2) OR (a = 1 AND c = 3)"},
+		{And(Or("a = 1", "b = 2"), Or("a = 1", "b = 2")), "a = 1 OR b = 2", "a = 1 OR b = 2"},
+		{Or(Eq("a", 1), And(Eq("b", 2), Eq("c", 3))), "(a = ? OR b = ?) AND (a = ? OR c = ?)", "a = ? OR (b = ? AND c = ?)"},
+		{And(Not(Or(Eq("a", 1), In("b", []int{1}))), Not(IsNull("c"))), "a <> ? AND b NOT IN (?) AND c IS NOT NULL", "a <> ? AND b NOT IN (?) AND c IS NOT NULL"},
+		{And(Not(And("a = 1", Not(Gt("b", 2))))), "NOT (a = 1) OR b > ?", "NOT (a = 1) OR b > ?"},
+	}
+	for _, test := range tests {
+		if str := test.c.CNF().String(); str != test.cnf {
+			t.Errorf("CNF() returned `%s` expected `%s`", str, test.cnf)
+		}
+		if str := test.c.DNF().String(); str != test.dnf {
+			t.Errorf("DNF() returned `%s` expected `%s`", str, test.dnf)
+		}
+	}
+}
+
+func Test_QueryNormalize(t *testing.T) {
+	q1 := Select().AddFrom("users").
+		AddJoin(Join{Table: "orders", On: And(And("orders.user_id = users.id"))}).
+		Where("a = 1").AndWhere("b = 2").AndWhere("a = 1")
+	expected := "SELECT * FROM users JOIN orders ON orders.user_id = users.id WHERE a = 1 AND b = 2"
+	if str := q1.Normalize().String(); str != expected {
+		t.Errorf("Normalize() returned `%s` expected `%s`", str, expected)
+	}
+
+	// strings with OR keep their parentheses so the query means the same
+	expected = "SELECT * FROM users WHERE (a = 1 OR b = 2) AND c = 3"
+	if str := Select().AddFrom("users").Where("a = 1 OR b = 2").AndWhere("c = 3").Normalize().String(); str != expected {
+		t.Errorf("Normalize() returned `%s` expected `%s`", str, expected)
+	}
+
+	expected = "SELECT * FROM users WHERE a = 1 AND b = 2 AND c = 3"
+	if str := Select().AddFrom("users").Where("a = 1").AndWhere("b = 2").AndWhere("c = 3").Normalize().String(); str != expected {
+		t.Errorf("Normalize() returned `%s` expected `%s`", str, expected)
+	}
+
+	Use(TenantScope("tenant_id", "users"), NormalizeCriteria())
+	defer ResetMiddleware()
+	expected = "SELECT * FROM users WHERE (a = 1 OR b = 2) AND users.tenant_id = ?"
+	if str := Select().AddFrom("users").Where("a = 1 OR b = 2").WithTenant(1).String(); str != expected {
+		t.Errorf("NormalizeCriteria() returned `%s` expected `%s`", str, expected)
+	}
+}
@@ -0,0 +1,722 @@
+package squiggle
+
+import (
+	"strconv"
+	"strings"
+)
+
+// words that can't be used as unquoted identifiers or aliases
+var reservedWords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "BETWEEN": true,
+	"BY": true, "CASE": true, "CROSS": true, "DESC": true, "DISTINCT": true,
+	"ELSE": true, "END": true, "EXCEPT": true, "EXISTS": true, "FALSE": true,
+	"FROM": true, "FULL": true, "GROUP": true, "HAVING": true, "IN": true,
+	"INNER": true, "INTERSECT": true, "IS": true, "JOIN": true, "LEFT": true,
+	"LIKE": true, "LIMIT": true, "NATURAL": true, "NOT": true, "NULL": true,
+	"NULLS": true, "OFFSET": true, "ON": true, "OR": true, "ORDER": true,
+	"OUTER": true, "RIGHT": true, "SELECT": true, "THEN": true, "TRUE": true,
+	"UNION": true, "WHEN": true, "WHERE": true,
+}
+
+type parser struct {
+	src     string
+	tokens  []token
+	pos     int
+	dialect Dialect
+	// the values of the placeholders in the SQL
+	args []interface{}
+	// the number of ? placeholders seen so far
+	positional int
+	// whether the SQL quotes identifiers, the query then quotes every
+	// identifier
+	quoted bool
+}
+
+// Parses a SELECT statement into a query so it can be modified further.
+// Identifiers in fields, tables, joins, groupings and orderings become
+// structured squiggle.Field, squiggle.From, squiggle.Join, squiggle.Grouping
+// and squiggle.Ordering values.  WHERE, HAVING and ON criteria become nested
+// squiggle.Criteria split on AND and OR, the individual conditions are kept
+// as strings.  Fields that aren't plain identifiers are kept as
+// Field.Expression.
+//
+// Placeholders become values bound like those of predicates, so criteria
+// added later are numbered after them.  A positional placeholder such as ?
+// or $2 is bound to its argument, without arguments it's bound to a Slot
+// named by its position such as Slot("2") to supply with Compile().  Named
+// placeholders such as :id become slots named id.
+//
+// Quoted identifiers are recognized using the quotes of the dialect and the
+// query quotes identifiers when the SQL does.  Unquoted identifiers are
+// then folded to lower case for PostgreSQL so quoting them doesn't change
+// what they refer to.  The result of parsing the output of String() renders
+// the same SQL again.  A *ParseError with the position of the problem is
+// returned for SQL that can't be parsed.
+//
+// 	q, err := squiggle.Parse("SELECT id, name FROM users WHERE active = $1 ORDER BY name", squiggle.PostgreSQL, true)
+// 	q.AndWhere(squiggle.Eq("tenant_id", 5)).Limit(10)
+// 	// => SELECT id, name FROM users WHERE (active = $1) AND (tenant_id = $2) ORDER BY name ASC LIMIT 10
+// 	//    []interface{}{true, 5}
+func Parse(sql string, dialect Dialect, args ...interface{}) (*Query, error) {
+	tokens, err := lex(sql, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{src: sql, tokens: tokens, dialect: dialect, args: args}
+	for _, t := range tokens {
+		if t.kind == tokenQuoted {
+			p.quoted = true
+		}
+	}
+	q, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().isSymbol(";") {
+		p.pos++
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+
+	p.setDialect(q, dialect)
+	return q, nil
+}
+
+// sets the dialect of a parsed query and its subqueries
+func (p *parser) setDialect(q *Query, dialect Dialect) {
+	q.dialect = dialect
+	q.dialect.Functions = copyFunctions(dialect.Functions)
+	q.placeholder = dialect.Placeholder
+	if p.quoted {
+		q.identifierLeftQuote = dialect.IdentifierLeftQuote
+		q.identifierRightQuote = dialect.IdentifierRightQuote
+	}
+	for _, from := range q.from {
+		if from.Subquery != nil {
+			p.setDialect(from.Subquery, dialect)
+		}
+	}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// consumes the next token if it's one of the keywords
+func (p *parser) accept(keywords ...string) bool {
+	for _, keyword := range keywords {
+		if p.peek().is(keyword) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(keyword string) error {
+	if !p.accept(keyword) {
+		return p.errorf(p.peek(), "expected %s", keyword)
+	}
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) *ParseError {
+	return newParseError(p.src, t.start, format, args...)
+}
+
+func (p *parser) unexpected(t token) *ParseError {
+	if t.kind == tokenEOF {
+		return p.errorf(t, "unexpected end of SQL")
+	}
+	return p.errorf(t, "unexpected %s", p.src[t.start:t.end])
+}
+
+// returns the source text of the tokens [start, end)
+func (p *parser) text(start, end int) string {
+	return p.src[p.tokens[start].start:p.tokens[end-1].end]
+}
+
+// reports whether the token can be used as an identifier
+func (p *parser) isIdentifier(t token) bool {
+	return t.kind == tokenQuoted || (t.kind == tokenWord && !reservedWords[strings.ToUpper(t.text)])
+}
+
+// returns the name of an identifier token
+func (p *parser) identifier(t token) string {
+	if t.kind == tokenWord && p.quoted && p.dialect.Name == PostgreSQL.Name {
+		// PostgreSQL folds unquoted identifiers, the query quotes them
+		return strings.ToLower(t.text)
+	}
+	return t.text
+}
+
+// returns the SQL of the tokens [start, end) as a string or, when it has
+// placeholders, as a Fragment binding their values
+func (p *parser) sql(start, end int) (interface{}, error) {
+	var fragment Fragment
+	from := p.tokens[start].start
+	for i := start; i < end; i++ {
+		t := p.tokens[i]
+		if t.kind != tokenPlaceholder {
+			continue
+		}
+		if t.start > from {
+			fragment = append(fragment, Raw(p.src[from:t.start]))
+		}
+		param, err := p.placeholder(t)
+		if err != nil {
+			return nil, err
+		}
+		fragment = append(fragment, param)
+		from = t.end
+	}
+	if fragment == nil {
+		return p.text(start, end), nil
+	}
+	if last := p.tokens[end-1].end; last > from {
+		fragment = append(fragment, Raw(p.src[from:last]))
+	}
+	return fragment, nil
+}
+
+// returns the value of a placeholder: its argument or, without arguments
+// or when it's named, a slot
+func (p *parser) placeholder(t token) (Param, error) {
+	name := t.text
+	if name == "?" {
+		p.positional++
+		name = strconv.Itoa(p.positional)
+	} else if prefix := p.dialect.Placeholder; len(prefix) > 1 && strings.HasPrefix(name, prefix) {
+		// numbered placeholders with a prefix such as @p1
+		name = name[len(prefix):]
+	} else {
+		name = name[1:]
+	}
+
+	n, err := strconv.Atoi(name)
+	if err != nil || n < 1 || len(p.args) == 0 {
+		return Param{Value: Slot(name)}, nil
+	}
+	if n > len(p.args) {
+		return Param{}, p.errorf(t, "no argument for placeholder %s", t.text)
+	}
+	return Param{Value: p.args[n-1]}, nil
+}
+
+// parses a dotted identifier such as schema.table.field of at most max
+// parts.  The parts are returned from left to right.
+func (p *parser) parsePath(max int, what string) ([]string, error) {
+	var parts []string
+	for {
+		t := p.peek()
+		if !p.isIdentifier(t) {
+			return nil, p.errorf(t, "expected %s", what)
+		}
+		p.pos++
+		parts = append(parts, p.identifier(t))
+		if !p.peek().isSymbol(".") {
+			break
+		}
+		if len(parts) == max {
+			return nil, p.errorf(p.peek(), "too many parts in %s", what)
+		}
+		p.pos++
+	}
+	return parts, nil
+}
+
+// parses an optional alias of a table with or without AS
+func (p *parser) parseAlias() (string, error) {
+	if p.accept("AS") {
+		t := p.next()
+		if !p.isIdentifier(t) {
+			return "", p.errorf(t, "expected alias")
+		}
+		return p.identifier(t), nil
+	}
+	if t := p.peek(); p.isIdentifier(t) {
+		p.pos++
+		return p.identifier(t), nil
+	}
+	return "", nil
+}
+
+func (p *parser) parseSelect() (*Query, error) {
+	q := Select()
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	if p.accept("DISTINCT") {
+		q.distinct = true
+	}
+	if err := p.parseFields(q); err != nil {
+		return nil, err
+	}
+
+	if p.accept("FROM") {
+		for {
+			from, err := p.parseFrom()
+			if err != nil {
+				return nil, err
+			}
+			q.from = append(q.from, from)
+			if !p.peek().isSymbol(",") {
+				break
+			}
+			p.pos++
+		}
+	}
+
+	for p.atJoin() {
+		join, err := p.parseJoin()
+		if err != nil {
+			return nil, err
+		}
+		q.joins = append(q.joins, join)
+	}
+
+	if p.accept("WHERE") {
+		where, err := p.parseCriteria()
+		if err != nil {
+			return nil, err
+		}
+		q.where = where
+	}
+
+	if p.accept("GROUP") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			path, err := p.parsePath(3, "grouping field")
+			if err != nil {
+				return nil, err
+			}
+			grouping := Grouping{Field: path[len(path)-1]}
+			if len(path) > 1 {
+				grouping.Table = path[len(path)-2]
+			}
+			if len(path) > 2 {
+				grouping.Schema = path[0]
+			}
+			q.groupings = append(q.groupings, grouping)
+			if !p.peek().isSymbol(",") {
+				break
+			}
+			p.pos++
+		}
+	}
+
+	if p.accept("HAVING") {
+		having, err := p.parseCriteria()
+		if err != nil {
+			return nil, err
+		}
+		q.having = having
+	}
+
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			ordering, err := p.parseOrdering()
+			if err != nil {
+				return nil, err
+			}
+			q.orderings = append(q.orderings, ordering)
+			if !p.peek().isSymbol(",") {
+				break
+			}
+			p.pos++
+		}
+	}
+
+	if p.accept("LIMIT") {
+		limit, err := p.parseInt()
+		if err != nil {
+			return nil, err
+		}
+		q.limit = limit
+		// MySQL's LIMIT <offset>, <limit>
+		if p.peek().isSymbol(",") {
+			p.pos++
+			q.offset = limit
+			if q.limit, err = p.parseInt(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if p.accept("OFFSET") {
+		offset, err := p.parseInt()
+		if err != nil {
+			return nil, err
+		}
+		q.offset = offset
+	}
+
+	return q, nil
+}
+
+func (p *parser) parseInt() (int, error) {
+	t := p.next()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != tokenNumber || err != nil {
+		return 0, p.errorf(t, "expected an integer")
+	}
+	return n, nil
+}
+
+// parses the fields of a SELECT up to FROM or the next clause.  A lone *
+// leaves the query without fields.
+func (p *parser) parseFields(q *Query) error {
+	start := p.pos
+	end := p.scan(func(t token) bool {
+		return t.is("FROM") || isClauseKeyword(t)
+	})
+	if start == end {
+		return p.errorf(p.peek(), "expected fields")
+	}
+	if end-start == 1 && p.tokens[start].isSymbol("*") {
+		return nil
+	}
+
+	for _, r := range p.split(start, end, func(t token) bool { return t.isSymbol(",") }) {
+		field, err := p.parseField(r[0], r[1])
+		if err != nil {
+			return err
+		}
+		q.fields = append(q.fields, field)
+	}
+	return nil
+}
+
+// parses a field from the tokens [start, end)
+func (p *parser) parseField(start, end int) (Field, error) {
+	var field Field
+	if start == end {
+		return field, p.errorf(p.tokens[start], "expected field")
+	}
+
+	// an alias with AS or an identifier directly after the expression
+	if end-start >= 3 && p.tokens[end-2].is("AS") {
+		if !p.isIdentifier(p.tokens[end-1]) {
+			return field, p.errorf(p.tokens[end-1], "expected alias")
+		}
+		field.Alias = p.identifier(p.tokens[end-1])
+		end = end - 2
+	} else if end-start >= 2 && p.isIdentifier(p.tokens[end-1]) && endsExpression(p.tokens[end-2]) {
+		field.Alias = p.identifier(p.tokens[end-1])
+		end = end - 1
+	}
+
+	if path, ok := p.pathAt(start, end); ok && len(path) <= 3 {
+		field.Name = path[len(path)-1]
+		if len(path) > 1 {
+			field.Table = path[len(path)-2]
+		}
+		if len(path) > 2 {
+			field.Schema = path[0]
+		}
+		return field, nil
+	}
+
+	expression, err := p.sql(start, end)
+	if err != nil {
+		return field, err
+	}
+	if fragment, ok := expression.(Fragment); ok {
+		field.Expr = fragment
+	} else {
+		field.Expression = expression.(string)
+	}
+	return field, nil
+}
+
+// reports whether a token can be the last token of an expression, used to
+// tell an alias apart from part of an expression
+func endsExpression(t token) bool {
+	switch t.kind {
+	case tokenQuoted, tokenString, tokenNumber, tokenPlaceholder:
+		return true
+	case tokenWord:
+		return !reservedWords[strings.ToUpper(t.text)] || t.is("END") || t.is("NULL") || t.is("TRUE") || t.is("FALSE")
+	}
+	return t.isSymbol(")")
+}
+
+// returns the parts of a dotted identifier when the tokens [start, end) are
+// exactly one
+func (p *parser) pathAt(start, end int) ([]string, bool) {
+	if (end-start)%2 == 0 {
+		return nil, false
+	}
+	var parts []string
+	for i := start; i < end; i++ {
+		t := p.tokens[i]
+		if (i-start)%2 == 1 {
+			if !t.isSymbol(".") {
+				return nil, false
+			}
+			continue
+		}
+		if !p.isIdentifier(t) {
+			return nil, false
+		}
+		parts = append(parts, p.identifier(t))
+	}
+	return parts, true
+}
+
+func (p *parser) parseFrom() (From, error) {
+	var from From
+	if p.peek().isSymbol("(") {
+		p.pos++
+		subquery, err := p.parseSelect()
+		if err != nil {
+			return from, err
+		}
+		if t := p.next(); !t.isSymbol(")") {
+			return from, p.errorf(t, "expected )")
+		}
+		from.Subquery = subquery
+	} else {
+		path, err := p.parsePath(2, "table")
+		if err != nil {
+			return from, err
+		}
+		from.Table = path[len(path)-1]
+		if len(path) > 1 {
+			from.Schema = path[0]
+		}
+	}
+
+	alias, err := p.parseAlias()
+	from.Alias = alias
+	return from, err
+}
+
+// reports whether the parser is at the start of a join
+func (p *parser) atJoin() bool {
+	t := p.peek()
+	if t.is("JOIN") || t.is("INNER") || t.is("CROSS") || t.is("FULL") || t.is("NATURAL") {
+		return true
+	}
+	// LEFT and RIGHT are also functions
+	if t.is("LEFT") || t.is("RIGHT") {
+		next := p.tokens[p.pos+1]
+		return next.is("JOIN") || next.is("OUTER")
+	}
+	return false
+}
+
+func (p *parser) parseJoin() (Join, error) {
+	var join Join
+	var types []string
+	for !p.peek().is("JOIN") {
+		t := p.next()
+		if t.kind != tokenWord {
+			return join, p.errorf(t, "expected JOIN")
+		}
+		types = append(types, strings.ToUpper(t.text))
+	}
+	p.pos++
+	join.Type = strings.Join(types, " ")
+
+	if p.peek().isSymbol("(") {
+		return join, p.errorf(p.peek(), "subqueries in JOIN are not supported")
+	}
+	path, err := p.parsePath(2, "table")
+	if err != nil {
+		return join, err
+	}
+	join.Table = path[len(path)-1]
+	if len(path) > 1 {
+		join.Schema = path[0]
+	}
+	if join.Alias, err = p.parseAlias(); err != nil {
+		return join, err
+	}
+
+	if p.accept("ON") {
+		if join.On, err = p.parseCriteria(); err != nil {
+			return join, err
+		}
+	}
+	return join, nil
+}
+
+func (p *parser) parseOrdering() (Ordering, error) {
+	var ordering Ordering
+	path, err := p.parsePath(3, "ordering field")
+	if err != nil {
+		return ordering, err
+	}
+	ordering.Field = path[len(path)-1]
+	if len(path) > 1 {
+		ordering.Table = path[len(path)-2]
+	}
+	if len(path) > 2 {
+		ordering.Schema = path[0]
+	}
+
+	if p.accept("DESC") {
+		ordering.Desc = true
+	} else {
+		p.accept("ASC")
+	}
+	if p.accept("NULLS") {
+		t := p.next()
+		if !t.is("FIRST") && !t.is("LAST") {
+			return ordering, p.errorf(t, "expected FIRST or LAST")
+		}
+		ordering.Nulls = strings.ToUpper(t.text)
+	}
+	return ordering, nil
+}
+
+// reports whether a token starts a clause that ends a list of fields or
+// criteria
+func isClauseKeyword(t token) bool {
+	for _, keyword := range []string{"WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "UNION", "INTERSECT", "EXCEPT", "JOIN", "INNER", "CROSS", "FULL", "NATURAL", "ON"} {
+		if t.is(keyword) {
+			return true
+		}
+	}
+	return t.isSymbol(";")
+}
+
+// advances the parser to the first token outside of parentheses for which
+// stop returns true, to an unmatched ) or to the end of the SQL and returns
+// the position it stopped at
+func (p *parser) scan(stop func(t token) bool) int {
+	depth := 0
+	for {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return p.pos
+		}
+		if depth == 0 && (stop(t) || t.isSymbol(")") || p.atJoin()) {
+			return p.pos
+		}
+		if t.isSymbol("(") {
+			depth++
+		} else if t.isSymbol(")") {
+			depth--
+		}
+		p.pos++
+	}
+}
+
+// splits the tokens [start, end) on separators outside of parentheses and
+// CASE expressions.  The separators aren't included in the ranges.
+func (p *parser) split(start, end int, separator func(t token) bool) [][2]int {
+	var ranges [][2]int
+	depth, cases, between := 0, 0, false
+	from := start
+	for i := start; i < end; i++ {
+		t := p.tokens[i]
+		switch {
+		case t.isSymbol("("):
+			depth++
+		case t.isSymbol(")"):
+			depth--
+		case t.is("CASE"):
+			cases++
+		case t.is("END"):
+			cases--
+		case depth == 0 && t.is("BETWEEN"):
+			between = true
+		case depth == 0 && cases == 0 && between && t.is("AND"):
+			// the AND of BETWEEN x AND y
+			between = false
+		case depth == 0 && cases == 0 && separator(t):
+			ranges = append(ranges, [2]int{from, i})
+			from = i + 1
+		}
+	}
+	return append(ranges, [2]int{from, end})
+}
+
+// parses criteria up to the next clause
+func (p *parser) parseCriteria() (Criteria, error) {
+	start := p.pos
+	end := p.scan(isClauseKeyword)
+	if start == end {
+		return Criteria{}, p.errorf(p.peek(), "expected criteria")
+	}
+
+	c, err := p.criteria(start, end)
+	if err != nil {
+		return Criteria{}, err
+	}
+	if criteria, ok := c.(Criteria); ok {
+		return criteria, nil
+	}
+	return And(c), nil
+}
+
+// parses the tokens [start, end) into a Criteria or a string for a single
+// condition
+func (p *parser) criteria(start, end int) (interface{}, error) {
+	if start == end {
+		return nil, p.errorf(p.tokens[start], "expected criteria")
+	}
+
+	for _, logic := range []string{"OR", "AND"} {
+		ranges := p.split(start, end, func(t token) bool { return t.is(logic) })
+		if len(ranges) == 1 {
+			continue
+		}
+		c := Criteria{and: logic == "AND"}
+		for _, r := range ranges {
+			expression, err := p.criteria(r[0], r[1])
+			if err != nil {
+				return nil, err
+			}
+			c.expressions = append(c.expressions, expression)
+		}
+		return c, nil
+	}
+
+	// a condition wrapped in parentheses is a nested criteria
+	if p.tokens[start].isSymbol("(") && p.closing(start) == end-1 && !p.tokens[start+1].is("SELECT") {
+		expression, err := p.criteria(start+1, end-1)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := expression.(Criteria); ok {
+			return c, nil
+		}
+		return And(expression), nil
+	}
+
+	return p.sql(start, end)
+}
+
+// returns the position of the ) matching the ( at start
+func (p *parser) closing(start int) int {
+	depth := 0
+	for i := start; i < len(p.tokens); i++ {
+		if p.tokens[i].isSymbol("(") {
+			depth++
+		} else if p.tokens[i].isSymbol(")") {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
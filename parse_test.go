@@ -0,0 +1,151 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	queries := []*Query{
+		Select().AddFrom("users"),
+		Select().
+			Distinct().
+			SetDialect(MySQL).
+			AddField("id", Field{Schema: "db", Table: "u", Name: "name", Alias: "n"}, Field{Expression: "COUNT(*)", Alias: "total"}).
+			AddFrom(From{Schema: "db", Table: "users", Alias: "u"}, "roles").
+			AddJoin(Join{Type: "left outer", Table: "teams", Alias: "t", On: And("t.id = u.team_id", Or("t.x BETWEEN 1 AND 2", "t.y IN (1, 2)"))}, Join{Table: "a"}).
+			Where(Or(Eq("u.status", "active"), And(Gt("age", 10), Like("name", "a%")))).
+			AndWhere("CASE WHEN a AND b THEN 1 END = 1").
+			AddGrouping("id", Grouping{Schema: "db", Table: "u", Field: "name"}).
+			Having("COUNT(*) > 1").
+			OrHaving(And("SUM(x) < 10")).
+			AddOrdering("id", Ordering{Table: "u", Field: "name", Desc: true, Nulls: "LAST"}).
+			Limit(10).
+			Offset(20),
+		Select().SetDialect(PostgreSQL).AddFrom("users").Where(In("id", []int{1, 2, 3})).CountQuery(),
+		Select().AddFrom("a").AddJoin(Join{Type: "natural", Table: "b"}, Join{Type: "natural left", Table: "c"}, Join{Type: "natural right outer", Table: "d", Alias: "x"}),
+	}
+
+	for _, q := range queries {
+		sql := q.String()
+		parsed, err := Parse(sql, q.Dialect())
+		if err != nil {
+			t.Errorf("Parse(`%s`) returned error %v", sql, err)
+			continue
+		}
+		if str := parsed.String(); str != sql {
+			t.Errorf("Parse() did not round-trip: `%s` became `%s`", sql, str)
+		}
+	}
+
+	q, err := Parse("SELECT u.id FROM db.users AS u WHERE a = 1 AND (b = 2 OR c = 3)", PostgreSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := q.fields[0]; f.Table != "u" || f.Name != "id" {
+		t.Errorf("Parse() returned unexpected field %+v", f)
+	}
+	if f := q.from[0]; f.Schema != "db" || f.Table != "users" || f.Alias != "u" {
+		t.Errorf("Parse() returned unexpected from %+v", f)
+	}
+	if c, ok := q.where.expressions[1].(Criteria); !q.where.and || !ok || c.and || c.expressions[1].(string) != "c = 3" {
+		t.Errorf("Parse() returned unexpected criteria %#v", q.where)
+	}
+	if str := q.String(); str != "SELECT u.id FROM db.users u WHERE a = 1 AND (b = 2 OR c = 3)" {
+		t.Errorf("Parse() returned unexpected query `%s`", str)
+	}
+}
+
+func Test_ParseError(t *testing.T) {
+	_, err := Parse("SELECT id\nFROM users\nWHERE a = 1 LIMIT x", PostgreSQL)
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("Parse() returned %v expected a *ParseError", err)
+	}
+	if perr.Offset != 39 || perr.Line != 3 || perr.Column != 19 {
+		t.Errorf("Parse() returned error at offset %d line %d column %d", perr.Offset, perr.Line, perr.Column)
+	}
+	if err.Error() != "squiggle: expected an integer at line 3, column 19" {
+		t.Errorf("Parse() returned unexpected error message `%s`", err)
+	}
+
+	if _, err := Parse(`SELECT "id FROM users`, PostgreSQL); err == nil {
+		t.Error("Parse() should return an error for an unterminated identifier")
+	}
+	if _, err := Parse(`SELECT id FROM users UNION SELECT id FROM admins`, PostgreSQL); err == nil {
+		t.Error("Parse() should return an error for UNION")
+	}
+}
+
+func Test_ParsePlaceholders(t *testing.T) {
+	q, err := Parse("SELECT id FROM users WHERE id = $1", PostgreSQL, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, args := q.AndWhere(Eq("org", 5)).ToSQL()
+	if sql != "SELECT id FROM users WHERE (id = $1) AND (org = $2)" {
+		t.Errorf("Parse() returned `%s` expected `%s`", sql, "SELECT id FROM users WHERE (id = $1) AND (org = $2)")
+	}
+	if len(args) != 2 || args[0] != 7 || args[1] != 5 {
+		t.Errorf("Parse() returned args %v expected [7 5]", args)
+	}
+
+	q, err = Parse("SELECT id FROM users WHERE a = ? AND b = :name", MySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, args = q.ToSQL()
+	if len(args) != 2 || args[0] != Slot("1") || args[1] != Slot("name") {
+		t.Errorf("Parse() returned args %v expected slots [1 name]", args)
+	}
+
+	if _, err := Parse("SELECT id FROM users WHERE id = $2", PostgreSQL, 7); err == nil {
+		t.Error("Parse() should return an error for a placeholder without argument")
+	}
+}
+
+func Test_ParseEscapes(t *testing.T) {
+	tests := []struct {
+		sql     string
+		dialect Dialect
+		ok      bool
+	}{
+		{`SELECT * FROM a WHERE b = 'it\'s'`, MySQL, true},
+		{`SELECT * FROM a WHERE b = 'a\\' AND c = 'd'`, MySQL, true},
+		{`SELECT * FROM a WHERE b = 'it''s'`, MySQL, true},
+		{`SELECT * FROM a WHERE b = 'a\'`, PostgreSQL, true},
+		{`SELECT * FROM a WHERE b = 'a\'`, MySQL, false},
+	}
+
+	for _, test := range tests {
+		q, err := Parse(test.sql, test.dialect)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("Parse(`%s`) did not return an error", test.sql)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(`%s`) returned error %v", test.sql, err)
+			continue
+		}
+		if str := q.String(); str != test.sql {
+			t.Errorf("Parse() did not round-trip: `%s` became `%s`", test.sql, str)
+		}
+	}
+
+	tokens, err := lex(`'it\'s' 'a\\'`, MySQL)
+	if err != nil || len(tokens) != 3 || tokens[0].text != `'it\'s'` || tokens[1].text != `'a\\'` {
+		t.Errorf("lex() returned unexpected tokens %v, error %v", tokens, err)
+	}
+}
+
+func Test_ParseQuoting(t *testing.T) {
+	q, err := Parse(`SELECT "Name", Email FROM Users`, PostgreSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `SELECT "Name", "email" FROM "users"`
+	if str := q.String(); str != expected {
+		t.Errorf("Parse() returned `%s` expected `%s`", str, expected)
+	}
+}
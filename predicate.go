@@ -0,0 +1,222 @@
+package squiggle
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A typed predicate comparing a field to a value.  Unlike criteria strings
+// the value of a predicate is bound to a placeholder when the query is
+// rendered.  Predicates can be used anywhere criteria are accepted.
+//
+// 	squiggle.Select().Where(squiggle.Predicate{Field: "age", Op: ">", Value: 30})
+// 	// => SELECT * WHERE age > ?
+type Predicate struct {
+	Schema string
+	Table  string
+	Field  string
+	Op     string
+	Value  interface{}
+}
+
+// Creates an equality predicate.  A nil value creates an IS NULL predicate
+// and a slice value creates an IN predicate.
+//
+// 	squiggle.Eq("status", "active")     // => status = ?
+// 	squiggle.Eq("deleted_at", nil)      // => deleted_at IS NULL
+// 	squiggle.Eq("id", []int{1, 2, 3})   // => id IN (?, ?, ?)
+func Eq(field string, value interface{}) Predicate {
+	if isNil(value) {
+		return IsNull(field)
+	}
+	if isList(value) {
+		return In(field, value)
+	}
+	return Predicate{Field: field, Op: "=", Value: value}
+}
+
+// Creates an inequality predicate.  A nil value creates an IS NOT NULL
+// predicate and a slice value creates a NOT IN predicate.
+func NotEq(field string, value interface{}) Predicate {
+	if isNil(value) {
+		return IsNotNull(field)
+	}
+	if isList(value) {
+		return NotIn(field, value)
+	}
+	return Predicate{Field: field, Op: "<>", Value: value}
+}
+
+// Creates a predicate of field < value
+func Lt(field string, value interface{}) Predicate {
+	return Predicate{Field: field, Op: "<", Value: value}
+}
+
+// Creates a predicate of field <= value
+func Lte(field string, value interface{}) Predicate {
+	return Predicate{Field: field, Op: "<=", Value: value}
+}
+
+// Creates a predicate of field > value
+func Gt(field string, value interface{}) Predicate {
+	return Predicate{Field: field, Op: ">", Value: value}
+}
+
+// Creates a predicate of field >= value
+func Gte(field string, value interface{}) Predicate {
+	return Predicate{Field: field, Op: ">=", Value: value}
+}
+
+// Creates a predicate of field IN (values...).  The value must be a slice.
+func In(field string, values interface{}) Predicate {
+	return Predicate{Field: field, Op: "IN", Value: values}
+}
+
+// Creates a predicate of field NOT IN (values...).  The value must be a slice.
+func NotIn(field string, values interface{}) Predicate {
+	return Predicate{Field: field, Op: "NOT IN", Value: values}
+}
+
+// Creates a predicate of field LIKE pattern
+func Like(field string, pattern string) Predicate {
+	return Predicate{Field: field, Op: "LIKE", Value: pattern}
+}
+
+// Creates a predicate of field NOT LIKE pattern
+func NotLike(field string, pattern string) Predicate {
+	return Predicate{Field: field, Op: "NOT LIKE", Value: pattern}
+}
+
+// Creates a predicate of field IS NULL
+func IsNull(field string) Predicate {
+	return Predicate{Field: field, Op: "IS NULL"}
+}
+
+// Creates a predicate of field IS NOT NULL
+func IsNotNull(field string) Predicate {
+	return Predicate{Field: field, Op: "IS NOT NULL"}
+}
+
+// Creates a criteria with AND logic containing an equality predicate for
+// every key of the map.  The predicates are ordered by key so the same map
+// always produces the same SQL.
+//
+// 	squiggle.EqMap(map[string]interface{}{"status": "active", "deleted_at": nil, "role": []string{"a", "b"}})
+// 	// => deleted_at IS NULL AND role IN (?, ?) AND status = ?
+func EqMap(m map[string]interface{}) Criteria {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	c := Criteria{and: true}
+	for _, key := range keys {
+		c.expressions = append(c.expressions, Eq(key, m[key]))
+	}
+
+	return c
+}
+
+// Creates a criteria with AND logic for query-by-example.  Every field of the
+// struct with a `db` tag that holds a non-zero value becomes an equality
+// predicate, untagged and zero valued fields are ignored.
+//
+// 	squiggle.EqStruct(User{Username: "bob"})
+// 	// => username = ?
+func EqStruct(s interface{}) Criteria {
+	rv, ok := structValue(s)
+	if !ok {
+		panic(fmt.Sprintf("unexpected type %T used in EqStruct()", s))
+	}
+
+	c := Criteria{and: true}
+	for _, column := range getStructInfo(rv.Type()).columns {
+		if !column.tagged {
+			continue
+		}
+		fv, ok := column.value(rv)
+		if !ok || fv.IsZero() {
+			continue
+		}
+		c.expressions = append(c.expressions, Eq(column.name, fv.Interface()))
+	}
+
+	return c
+}
+
+// writes the predicate, its values are bound to placeholders
+func (p Predicate) writeSQL(q *Query, w *sqlWriter) {
+	op := strings.ToUpper(p.Op)
+	if op == "IN" || op == "NOT IN" {
+		values := listValues(p.Value)
+		if len(values) == 0 {
+			// nothing is IN an empty list and everything is NOT IN it
+			if op == "IN" {
+				w.write("1 = 0")
+			} else {
+				w.write("1 = 1")
+			}
+			return
+		}
+
+		q.writeQualified(w, p.Schema, p.Table, p.Field)
+		w.write(" ")
+		w.write(q.keyword(op))
+		w.write(" (")
+		for i, value := range values {
+			if i > 0 {
+				w.write(", ")
+			}
+			q.bind(w, value)
+		}
+		w.write(")")
+		return
+	}
+
+	q.writeQualified(w, p.Schema, p.Table, p.Field)
+	w.write(" ")
+	w.write(q.keyword(op))
+	if op != "IS NULL" && op != "IS NOT NULL" {
+		w.write(" ")
+		q.bind(w, p.Value)
+	}
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// reports whether value is a slice or array that should be expanded into a
+// list. []byte is treated as a single value.
+func isList(value interface{}) bool {
+	if _, ok := value.([]byte); ok {
+		return false
+	}
+	kind := reflect.ValueOf(value).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// returns the elements of a slice or array value.  A single value is
+// returned as a list of one.
+func listValues(value interface{}) []interface{} {
+	if !isList(value) {
+		return []interface{}{value}
+	}
+	rv := reflect.ValueOf(value)
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
@@ -0,0 +1,61 @@
+package squiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Eq(t *testing.T) {
+	if p := Eq("a", 1); p.Op != "=" || p.Value != 1 {
+		t.Error("Eq() did not create an equality predicate")
+	}
+	if p := Eq("a", nil); p.Op != "IS NULL" {
+		t.Error("Eq() with a nil value should create an IS NULL predicate")
+	}
+	if p := Eq("a", []int{1, 2}); p.Op != "IN" {
+		t.Error("Eq() with a slice value should create an IN predicate")
+	}
+	if p := Eq("a", []byte("x")); p.Op != "=" {
+		t.Error("Eq() with a []byte value should create an equality predicate")
+	}
+}
+
+func Test_PredicateToSQL(t *testing.T) {
+	q := Select().
+		SetIdentifierQuotes(`"`).
+		SetPlaceholder("$").
+		Where(And(Predicate{Table: "u", Field: "age", Op: ">=", Value: 21}, In("role", []string{"a", "b"}), NotIn("id", []int{}), IsNotNull("email")))
+
+	sql, args := q.ToSQL()
+	expected := `SELECT * WHERE "u"."age" >= $1 AND "role" IN ($2, $3) AND 1 = 1 AND "email" IS NOT NULL`
+	if sql != expected {
+		t.Errorf("ToSQL() returned `%s` expected `%s`", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{21, "a", "b"}) {
+		t.Errorf("ToSQL() returned unexpected args %v", args)
+	}
+}
+
+func Test_EqMap(t *testing.T) {
+	sql, args := EqMap(map[string]interface{}{"status": "active", "deleted_at": nil, "role": []string{"a", "b"}}).ToSQL()
+
+	expected := "deleted_at IS NULL AND role IN (?, ?) AND status = ?"
+	if sql != expected {
+		t.Errorf("EqMap() returned `%s` expected `%s`", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a", "b", "active"}) {
+		t.Errorf("EqMap() returned unexpected args %v", args)
+	}
+}
+
+func Test_EqStruct(t *testing.T) {
+	sql, args := EqStruct(testUser{Username: "bob", Nickname: "b"}).ToSQL()
+
+	expected := "username = ?"
+	if sql != expected {
+		t.Errorf("EqStruct() returned `%s` expected `%s`", sql, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"bob"}) {
+		t.Errorf("EqStruct() returned unexpected args %v", args)
+	}
+}
@@ -19,21 +19,23 @@ package squiggle
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
 type Join struct {
-	Type   string
-	On     Criteria
-	Schema string
-	Table  string
-	Alias  string
+	Type   string   `json:"type,omitempty"`
+	On     Criteria `json:"on"`
+	Schema string   `json:"schema,omitempty"`
+	Table  string   `json:"table"`
+	Alias  string   `json:"alias,omitempty"`
 }
 
 type From struct {
-	Schema string
-	Table  string
-	Alias  string
+	Schema   string `json:"schema,omitempty"`
+	Table    string `json:"table,omitempty"`
+	Alias    string `json:"alias,omitempty"`
+	Subquery *Query `json:"subquery,omitempty"`
 }
 
 type Field struct {
@@ -41,20 +43,22 @@ type Field struct {
 	Table      string
 	Name       string
 	Expression string
+	Expr       Expression
 	Alias      string
 }
 
 type Grouping struct {
-	Schema string
-	Table  string
-	Field  string
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table,omitempty"`
+	Field  string `json:"field"`
 }
 
 type Ordering struct {
-	Schema string
-	Table  string
-	Field  string
-	Desc   bool
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table,omitempty"`
+	Field  string `json:"field"`
+	Desc   bool   `json:"desc,omitempty"`
+	Nulls  string `json:"nulls,omitempty"`
 }
 
 type Query struct {
@@ -68,8 +72,26 @@ type Query struct {
 	having               Criteria
 	limit                int
 	offset               int
+	distinct             bool
 	identifierLeftQuote  string
 	identifierRightQuote string
+	placeholder          string
+	dialect              Dialect
+	immutable            bool
+	formatter            *formatter
+	insertColumns        []string
+	insertRows           [][]interface{}
+	hardDelete           bool
+	strictIdentifiers    bool
+	allowedIdentifiers   []string
+	strictValidation     bool
+	tags                 map[string]string
+	commentPosition      CommentPosition
+	middleware           []Middleware
+	skipMiddleware       []string
+	skipAllMiddleware    bool
+	middlewareApplied    bool
+	values               map[interface{}]interface{}
 }
 
 // Create a new SELECT query
@@ -95,6 +117,7 @@ func Select() *Query {
 // 	squiggle.Select().SetIdentifierQuotes("[", "]").Add(sqiggle.Field{Schema: "db1", Table: "users", Name: "field", Alias: "f1"}).AddFrom("users").String()
 // 	// => "SELECT [db1].[users].[field] AS [f1] FROM [users]"
 func (q *Query) SetIdentifierQuotes(quotes ...string) *Query {
+	q = q.builder()
 	if len(quotes) == 1 {
 		q.identifierLeftQuote = quotes[0]
 		q.identifierRightQuote = quotes[0]
@@ -106,8 +129,32 @@ func (q *Query) SetIdentifierQuotes(quotes ...string) *Query {
 	return q
 }
 
+// Sets the placeholder used for bound values.  The default is "?".  Any
+// other prefix creates numbered placeholders such as "$1" for PostgreSQL or
+// "@p1" for SQL Server.
+//
+// 	squiggle.Insert("users").SetPlaceholder("$").Values(user).String()
+// 	// => INSERT INTO users (id, username) VALUES ($1, $2)
+func (q *Query) SetPlaceholder(placeholder string) *Query {
+	q = q.builder()
+	q.placeholder = placeholder
+	return q
+}
+
+// Make a query select only distinct rows
+//
+// 	squiggle.Select().Distinct().AddField("country").AddFrom("users")
+// 	// => SELECT DISTINCT country FROM users
+func (q *Query) Distinct() *Query {
+	q = q.builder()
+	q.distinct = true
+
+	return q
+}
+
 // Set a limit on a query
 func (q *Query) Limit(l int) *Query {
+	q = q.builder()
 	q.limit = l
 
 	return q
@@ -115,6 +162,7 @@ func (q *Query) Limit(l int) *Query {
 
 // Set an offset on a query
 func (q *Query) Offset(o int) *Query {
+	q = q.builder()
 	q.offset = o
 
 	return q
@@ -129,6 +177,7 @@ func (q *Query) Offset(o int) *Query {
 // 	sqiggle.Select().AddFrom("foo")
 // 	sqiggle.Select().AddFrom(sqiggle.From{Table: "foo"})
 func (q *Query) AddFrom(froms ...interface{}) *Query {
+	q = q.builder()
 	for _, from := range froms {
 		switch from.(type) {
 		default:
@@ -149,6 +198,7 @@ func (q *Query) AddFrom(froms ...interface{}) *Query {
 // 	sqiggle.Select().SetIdentifierQuotes(`"`).AddField("user_type", Field{Expression: "AVG(age)"})
 // 	// => SELECT "user_type", AVG(age)
 func (q *Query) AddField(fields ...interface{}) *Query {
+	q = q.builder()
 	for _, field := range fields {
 		switch field.(type) {
 		default:
@@ -170,6 +220,7 @@ func (q *Query) AddField(fields ...interface{}) *Query {
 // 	squiggle.Select().AddOrdering("foo", squiggle.Ordering{Field: "Bar", Desc: true})
 // 	// => SELECT ... ORDER BY foo ASC, Bar DESC
 func (q *Query) AddOrdering(orderings ...interface{}) *Query {
+	q = q.builder()
 	for _, ordering := range orderings {
 		switch ordering.(type) {
 		default:
@@ -191,6 +242,7 @@ func (q *Query) AddOrdering(orderings ...interface{}) *Query {
 // 	squiggle.Select().AddGrouping("foo", Grouping{Field: "bar", Table: "baz"})
 // 	// => SELECT ... GROUP BY foo, baz.bar
 func (q *Query) AddGrouping(groupings ...interface{}) *Query {
+	q = q.builder()
 	for _, grouping := range groupings {
 		switch grouping.(type) {
 		default:
@@ -206,6 +258,7 @@ func (q *Query) AddGrouping(groupings ...interface{}) *Query {
 
 // Add joins to a query.  Accepts any number of arguments of type Join
 func (q *Query) AddJoin(j ...Join) *Query {
+	q = q.builder()
 	q.joins = append(q.joins, j...)
 	return q
 }
@@ -219,15 +272,15 @@ func (q *Query) Add(things ...interface{}) *Query {
 		default:
 			panic(fmt.Sprintf("unexpected type %T used in Add()", thing))
 		case Grouping:
-			q.AddGrouping(thing.(Grouping))
+			q = q.AddGrouping(thing.(Grouping))
 		case Ordering:
-			q.AddOrdering(thing.(Ordering))
+			q = q.AddOrdering(thing.(Ordering))
 		case Field:
-			q.AddField(thing.(Field))
+			q = q.AddField(thing.(Field))
 		case From:
-			q.AddFrom(thing.(From))
+			q = q.AddFrom(thing.(From))
 		case Join:
-			q.AddJoin(thing.(Join))
+			q = q.AddJoin(thing.(Join))
 		}
 	}
 
@@ -236,194 +289,251 @@ func (q *Query) Add(things ...interface{}) *Query {
 
 // returns the fields and expressions portions of the query as an SQL string
 func (q *Query) FieldsString() string {
-	sql := ""
-	var fields []string
+	w := &sqlWriter{}
+	q.writeFields(w)
+	return w.String()
+}
+
+// writes the fields and expressions portions of the query
+func (q *Query) writeFields(w *sqlWriter) {
 	if len(q.fields) == 0 {
-		sql = sql + " *"
-	} else {
-		for _, field := range q.fields {
-			fieldStr := ""
-			if field.Expression == "" {
-				if field.Schema != `` {
-					fieldStr = fieldStr + q.identfierQuote(field.Schema) + "."
-				}
-				if field.Table != `` {
-					fieldStr = fieldStr + q.identfierQuote(field.Table) + "."
-				}
-				fieldStr = fieldStr + q.identfierQuote(field.Name)
-			} else {
-				fieldStr = fieldStr + field.Expression
-			}
-			if field.Alias != `` {
-				fieldStr = fieldStr + " AS " + q.identfierQuote(field.Alias)
-			}
-			fields = append(fields, fieldStr)
-		}
-		sql = sql + " " + strings.Join(fields, ", ")
+		w.write(q.listSeparator(0))
+		w.write("*")
+		return
 	}
 
-	return sql
+	for i, field := range q.fields {
+		w.write(q.listSeparator(i))
+		if field.Expr != nil {
+			field.Expr.expressionSQL(q, w)
+		} else if field.Expression == "" {
+			q.writeQualified(w, field.Schema, field.Table, field.Name)
+		} else {
+			w.write(field.Expression)
+		}
+		if field.Alias != `` {
+			w.write(" ")
+			w.write(q.keyword("AS"))
+			w.write(" ")
+			q.writePart(w, field.Alias)
+		}
+	}
 }
 
 // returns the from portion of the query as an SQL string
 func (q *Query) FromString() string {
-	sql := ""
-	if len(q.from) > 0 {
-		var fromStrings []string
-		sql = sql + " FROM "
-		for _, from := range q.from {
-			fromStr := ""
-			if from.Schema != "" {
-				fromStr = fromStr + q.identfierQuote(from.Schema) + "."
-			}
-			fromStr = fromStr + q.identfierQuote(from.Table)
-			if from.Alias != "" {
-				fromStr = fromStr + " " + q.identfierQuote(from.Alias)
-			}
-			fromStrings = append(fromStrings, fromStr)
-		}
-		sql = sql + strings.Join(fromStrings, ", ")
+	w := &sqlWriter{}
+	q.writeFrom(w)
+	return w.String()
+}
+
+// writes the from portion of the query
+func (q *Query) writeFrom(w *sqlWriter) {
+	if len(q.from) == 0 {
+		return
 	}
 
-	return sql
+	w.write(q.line(0))
+	w.write(q.keyword("FROM"))
+	for i, from := range q.from {
+		w.write(q.listSeparator(i))
+		if from.Subquery != nil {
+			q.writeSubquery(w, from.Subquery)
+		} else {
+			q.writeQualified(w, from.Schema, "", from.Table)
+		}
+		if from.Alias != "" {
+			w.write(" ")
+			q.writePart(w, from.Alias)
+		}
+	}
 }
 
 // returns the joins portion of the query as a string
 func (q *Query) JoinsString() string {
-	sql := ""
-	joinStrings := []string{}
+	w := &sqlWriter{}
+	q.writeJoins(w)
+	return w.String()
+}
+
+// writes the joins portion of the query
+func (q *Query) writeJoins(w *sqlWriter) {
 	for _, join := range q.joins {
-		joinStr := " " + strings.ToUpper(join.Type) + " JOIN "
-		if join.Schema != "" {
-			joinStr = joinStr + q.identfierQuote(join.Schema) + "."
+		w.write(q.line(0))
+		if join.Type != "" {
+			w.write(q.keyword(strings.ToUpper(join.Type)))
+			w.write(" ")
 		}
-		joinStr = joinStr + q.identfierQuote(join.Table)
+		w.write(q.keyword("JOIN"))
+		w.write(" ")
+		q.writeQualified(w, join.Schema, "", join.Table)
 		if join.Alias != "" {
-			joinStr = joinStr + " " + q.identfierQuote(join.Alias)
+			w.write(" ")
+			q.writePart(w, join.Alias)
 		}
 		if len(join.On.expressions) > 0 {
-			joinStr = joinStr + " ON " + join.On.String()
+			w.write(q.line(1))
+			w.write(q.keyword("ON"))
+			w.write(" ")
+			join.On.writeSQL(q, w, 1)
 		}
-		joinStrings = append(joinStrings, joinStr)
 	}
-
-	sql = sql + strings.Join(joinStrings, " ")
-
-	return sql
 }
 
 // returns the grouping portion of the query as a string
 func (q *Query) GroupingsString() string {
-	sql := ""
-	if len(q.groupings) > 0 {
-		sql = sql + " GROUP BY "
-		var groupingsStrings []string
-		for _, grouping := range q.groupings {
-			groupingStr := q.identfierQuote(grouping.Field)
-			if grouping.Table != "" {
-				groupingStr = q.identfierQuote(grouping.Table) + "." + groupingStr
-			}
-			if grouping.Schema != "" {
-				groupingStr = q.identfierQuote(grouping.Schema) + "." + groupingStr
-			}
-			groupingsStrings = append(groupingsStrings, groupingStr)
-		}
-		sql = sql + strings.Join(groupingsStrings, ", ")
+	w := &sqlWriter{}
+	q.writeGroupings(w)
+	return w.String()
+}
+
+// writes the grouping portion of the query
+func (q *Query) writeGroupings(w *sqlWriter) {
+	if len(q.groupings) == 0 {
+		return
 	}
 
-	return sql
+	w.write(q.line(0))
+	w.write(q.keyword("GROUP BY"))
+	for i, grouping := range q.groupings {
+		w.write(q.listSeparator(i))
+		q.writeQualified(w, grouping.Schema, grouping.Table, grouping.Field)
+	}
 }
 
 // 	returns the orderings portion of the query as a string
 func (q *Query) OrderingsString() string {
-	sql := ""
-	if len(q.orderings) > 0 {
-		var orderingsStrings []string
-		for _, ordering := range q.orderings {
-			orderingStr := q.identfierQuote(ordering.Field)
-			if ordering.Table != "" {
-				orderingStr = q.identfierQuote(ordering.Table) + "." + orderingStr
-			}
-			if ordering.Schema != "" {
-				orderingStr = q.identfierQuote(ordering.Schema) + "." + orderingStr
-			}
-			if ordering.Desc {
-				orderingStr = orderingStr + " DESC"
-			} else {
-				orderingStr = orderingStr + " ASC"
-			}
-			orderingsStrings = append(orderingsStrings, orderingStr)
-		}
-		sql = sql + " ORDER BY " + strings.Join(orderingsStrings, ", ")
+	w := &sqlWriter{}
+	q.writeOrderings(w)
+	return w.String()
+}
+
+// writes the orderings portion of the query
+func (q *Query) writeOrderings(w *sqlWriter) {
+	if len(q.orderings) == 0 {
+		return
 	}
 
-	return sql
+	w.write(q.line(0))
+	w.write(q.keyword("ORDER BY"))
+	for i, ordering := range q.orderings {
+		w.write(q.listSeparator(i))
+		q.writeQualified(w, ordering.Schema, ordering.Table, ordering.Field)
+		w.write(" ")
+		if ordering.Desc {
+			w.write(q.keyword("DESC"))
+		} else {
+			w.write(q.keyword("ASC"))
+		}
+		if ordering.Nulls != "" {
+			w.write(" ")
+			w.write(q.keyword("NULLS " + strings.ToUpper(ordering.Nulls)))
+		}
+	}
 }
 
 // Turns the query into a string of SQL
 func (q *Query) String() string {
+	sql, _ := q.ToSQL()
+	return sql
+}
+
+// Turns the query into a string of SQL along with the values bound to its
+// placeholders
+//
+// 	sql, args := squiggle.Insert("users").Values(user).ToSQL()
+// 	db.Exec(sql, args...)
+func (q *Query) ToSQL() (string, []interface{}) {
+	w := &sqlWriter{}
+	q.writeSQL(w, " ")
+	return w.String(), w.args
+}
+
+// writes the query without its comment
+func (q *Query) toSQL(w *sqlWriter) {
+	if rewritten := q.applyMiddleware(); rewritten != q {
+		rewritten.toSQL(w)
+		return
+	}
+
+	if q.queryType == "INSERT" {
+		q.insertSQL(w)
+		return
+	}
+	if q.queryType == "DELETE" {
+		q.deleteSQL(w)
+		return
+	}
+
 	// <QUERY TYPE>
-	sql := q.queryType
+	w.write(q.keyword(q.queryType))
+	if q.distinct {
+		w.write(" ")
+		w.write(q.keyword("DISTINCT"))
+	}
 
 	// <FIELDS>
-	sql = sql + q.FieldsString()
+	q.writeFields(w)
 
 	// <FROM>
-	sql = sql + q.FromString()
+	q.writeFrom(w)
 
 	// <JOINS>
-	sql = sql + q.JoinsString()
+	q.writeJoins(w)
 
 	// <WHERE>
-	if len(q.where.expressions) > 0 {
-		sql = sql + " WHERE " + q.where.String()
-	}
+	q.writeCriteria(w, "WHERE", q.where)
 
 	// <GROUPS>
-	sql = sql + q.GroupingsString()
+	q.writeGroupings(w)
 
 	// <HAVING>
-	if len(q.having.expressions) > 0 {
-		sql = sql + " HAVING " + q.having.String()
-	}
+	q.writeCriteria(w, "HAVING", q.having)
 
 	// <ORDER>
-	sql = sql + q.OrderingsString()
+	q.writeOrderings(w)
 
 	// <LIMIT OFFSET>
 	if q.limit > 0 {
-		sql = sql + fmt.Sprintf(" LIMIT %d", q.limit)
+		w.write(q.line(0))
+		w.write(q.keyword("LIMIT"))
+		w.write(" ")
+		w.buf = strconv.AppendInt(w.buf, int64(q.limit), 10)
 	}
 	if q.offset > 0 {
-		sql = sql + fmt.Sprintf(" OFFSET %d", q.offset)
+		w.write(q.line(0))
+		w.write(q.keyword("OFFSET"))
+		w.write(" ")
+		w.buf = strconv.AppendInt(w.buf, int64(q.offset), 10)
 	}
+}
 
-	return sql
+// writes a clause holding criteria such as WHERE unless the criteria are
+// empty
+func (q *Query) writeCriteria(w *sqlWriter, keyword string, c Criteria) {
+	if len(c.expressions) == 0 {
+		return
+	}
+	w.write(q.line(0))
+	w.write(q.keyword(keyword))
+	w.write(q.listSeparator(0))
+	c.writeSQL(q, w, 1)
 }
 
 // Add criteria to the "where" portion of a query.  This method accepts a
-// parameter of type string or squiggle.Criteria.  The criteria can be
-// created by using the squiggle.And and squiggle.Or functions.  When an
-// argument of type string is passed it's the same as passing
-// squiggle.And("<string>")  Note that Where will replace and previously
-// created criteria.
+// parameter of type string, squiggle.Criteria or squiggle.Expression.  The
+// criteria can be created by using the squiggle.And and squiggle.Or
+// functions.  When an argument of type string or squiggle.Expression is
+// passed it's the same as passing squiggle.And(<argument>)  Note that Where
+// will replace and previously created criteria.
 //
 // 	squiggle.Select().Where("a=?")
 // 	// => WHERE a=?
 // 	squiggle.Select().Where(squiggle.Or("a=?", squiggle.And("b=?", "c=?)))
 // 	// => WHERE a=? OR (b=? AND c=?)
 func (q *Query) Where(c interface{}) *Query {
-	var criteria Criteria
-	switch c.(type) {
-	default:
-		panic(fmt.Sprintf("unexpected type %T used in Where()", c))
-	case string:
-		criteria = And(c.(string))
-	case Criteria:
-		criteria = c.(Criteria)
-	}
-
-	q.where = criteria
+	q = q.builder()
+	q.where = toCriteria(c, "Where")
 	return q
 }
 
@@ -432,15 +542,8 @@ func (q *Query) Where(c interface{}) *Query {
 // 	squiggle.Select().Where("a=1").AndWhere("b=2")
 // 	// => SELECT ... WHERE a=1 AND (b=2)
 func (q *Query) AndWhere(c interface{}) *Query {
-	var criteria Criteria
-	switch c.(type) {
-	default:
-		panic(fmt.Sprintf("unexpected type %T used in AndWhere()", c))
-	case string:
-		criteria = And(c.(string))
-	case Criteria:
-		criteria = c.(Criteria)
-	}
+	q = q.builder()
+	criteria := toCriteria(c, "AndWhere")
 	
 	if len(q.where.expressions) == 0 {
 		q.where = criteria
@@ -455,15 +558,8 @@ func (q *Query) AndWhere(c interface{}) *Query {
 // 	squiggle.Select().Where("a=1").OrWhere("b=2")
 // 	// => SELECT ... WHERE a=1 OR (b=2)
 func (q *Query) OrWhere(c interface{}) *Query {
-	var criteria Criteria
-	switch c.(type) {
-	default:
-		panic(fmt.Sprintf("unexpected type %T used in OrWhere()", c))
-	case string:
-		criteria = And(c.(string))
-	case Criteria:
-		criteria = c.(Criteria)
-	}
+	q = q.builder()
+	criteria := toCriteria(c, "OrWhere")
 
 	if len(q.where.expressions) == 0 {
 		q.where = criteria
@@ -476,17 +572,8 @@ func (q *Query) OrWhere(c interface{}) *Query {
 // This is the same as the Where() method except it add criteria to the HAVING
 // portion of the query rather than the WHERE portion
 func (q *Query) Having(c interface{}) *Query {
-	var criteria Criteria
-	switch c.(type) {
-	default:
-		panic(fmt.Sprintf("unexpected type %T used in Having()", c))
-	case string:
-		criteria = And(c.(string))
-	case Criteria:
-		criteria = c.(Criteria)
-	}
-
-	q.having = criteria
+	q = q.builder()
+	q.having = toCriteria(c, "Having")
 	return q
 }
 
@@ -495,15 +582,8 @@ func (q *Query) Having(c interface{}) *Query {
 // 	squiggle.Select().Having("a=1").AndHaving("b=2")
 // 	// => SELECT ... HAVING a=1 AND (b=2)
 func (q *Query) AndHaving(c interface{}) *Query {
-	var criteria Criteria
-	switch c.(type) {
-	default:
-		panic(fmt.Sprintf("unexpected type %T used in AndHaving()", c))
-	case string:
-		criteria = And(c.(string))
-	case Criteria:
-		criteria = c.(Criteria)
-	}
+	q = q.builder()
+	criteria := toCriteria(c, "AndHaving")
 
 	if len(q.having.expressions) == 0 {
 		q.having = criteria
@@ -518,15 +598,8 @@ func (q *Query) AndHaving(c interface{}) *Query {
 // 	squiggle.Select().Having("a=1").OrHaving("b=2")
 // 	// => SELECT ... HAVING a=1 OR (b=2)
 func (q *Query) OrHaving(c interface{}) *Query {
-	var criteria Criteria
-	switch c.(type) {
-	default:
-		panic(fmt.Sprintf("unexpected type %T used in OrHaving()", c))
-	case string:
-		criteria = And(c.(string))
-	case Criteria:
-		criteria = c.(Criteria)
-	}
+	q = q.builder()
+	criteria := toCriteria(c, "OrHaving")
 
 	if len(q.having.expressions) == 0 {
 		q.having = criteria
@@ -535,7 +608,3 @@ func (q *Query) OrHaving(c interface{}) *Query {
 	}
 	return q
 }
-
-func (q *Query) identfierQuote(identifier string) string {
-	return q.identifierLeftQuote + identifier + q.identifierRightQuote
-}
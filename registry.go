@@ -0,0 +1,292 @@
+package squiggle
+
+import (
+	"strings"
+	"sync"
+)
+
+// Metadata about a table registered with RegisterTable()
+type Table struct {
+	Name string
+	// the schema used for the table when a query doesn't give one
+	Schema string
+	// the alias used for the table when a query doesn't give one and doesn't
+	// refer to the table by its name
+	Alias string
+	// the column holding the time a row was soft deleted, rows where it's
+	// NULL haven't been deleted
+	SoftDelete string
+	// the value DELETE queries set the soft delete column to,
+	// CURRENT_TIMESTAMP when nil.  It must not be NULL.
+	SoftDeleteValue Expression
+	// the columns of the table and their types.  When they're given
+	// Validate() checks the columns queries refer to exist, see
+	// Query.Validate().  The soft delete column is a column of the table
+	// without them but its type is only checked when they have it.
+	Columns map[string]ValueType
+}
+
+var (
+	tablesMutex sync.RWMutex
+	tables      map[string]Table
+)
+
+// Registers metadata about tables used when queries are rendered.  Tables
+// in FROM and JOIN without a schema or alias get the registered default
+// schema and alias, the alias only when the query doesn't refer to the table
+// by its name such as in users.active.  SELECT queries of tables with a soft delete column only
+// return rows where the column IS NULL, see WithDeleted() and
+// OnlyDeleted(), and DELETE queries of them set the column instead of
+// deleting rows, see HardDelete().
+//
+// 	squiggle.RegisterTable(squiggle.Table{Name: "users", Schema: "app", Alias: "u", SoftDelete: "deleted_at"})
+// 	squiggle.Select().AddFrom("users").String()
+// 	// => SELECT * FROM app.users u WHERE u.deleted_at IS NULL
+func RegisterTable(t ...Table) {
+	tablesMutex.Lock()
+	defer tablesMutex.Unlock()
+	if tables == nil {
+		tables = map[string]Table{}
+	}
+	for _, table := range t {
+		tables[table.Name] = table
+	}
+}
+
+// Removes all tables registered with RegisterTable()
+func ResetTables() {
+	tablesMutex.Lock()
+	defer tablesMutex.Unlock()
+	tables = nil
+}
+
+// returns the registered metadata of a table
+func lookupTable(name string) (Table, bool) {
+	tablesMutex.RLock()
+	defer tablesMutex.RUnlock()
+	table, ok := tables[name]
+	return table, ok
+}
+
+// returns the type of a column of the table and whether the type is known,
+// column names are compared ignoring case.  The soft delete column is always
+// a column, its type is only known when Columns has it.
+func (t Table) column(name string) (valueType ValueType, typed bool, ok bool) {
+	for column, valueType := range t.Columns {
+		if strings.EqualFold(column, name) {
+			return valueType, true, true
+		}
+	}
+	if t.SoftDelete != "" && strings.EqualFold(t.SoftDelete, name) {
+		return 0, false, true
+	}
+	return 0, false, false
+}
+
+// returns the middleware for registered tables, nil when no tables are
+// registered
+func tableMiddleware() []Middleware {
+	tablesMutex.RLock()
+	defer tablesMutex.RUnlock()
+	if len(tables) == 0 {
+		return nil
+	}
+	return []Middleware{{Name: "tables", Rewrite: tableDefaults}, {Name: "soft-delete", Rewrite: softDelete}}
+}
+
+// applies the default schema and alias of registered tables
+func tableDefaults(q *Query) *Query {
+	aliases := map[string]bool{}
+	for _, from := range q.from {
+		aliases[from.Alias] = true
+	}
+	for _, join := range q.joins {
+		aliases[join.Alias] = true
+	}
+	// aliases aren't portable in DELETE and INSERT queries
+	useAlias := q.queryType == "SELECT"
+
+	for i, from := range q.from {
+		table, ok := lookupTable(from.Table)
+		if from.Subquery != nil || !ok {
+			continue
+		}
+		if from.Schema == "" {
+			q.from[i].Schema = table.Schema
+		}
+		if useAlias && from.Alias == "" && table.Alias != "" && !aliases[table.Alias] && !refersTo(q, from.Table) {
+			q.from[i].Alias = table.Alias
+			aliases[table.Alias] = true
+		}
+	}
+	for i, join := range q.joins {
+		table, ok := lookupTable(join.Table)
+		if !ok {
+			continue
+		}
+		if join.Schema == "" {
+			q.joins[i].Schema = table.Schema
+		}
+		if join.Alias == "" && table.Alias != "" && !aliases[table.Alias] && !refersTo(q, join.Table) {
+			q.joins[i].Alias = table.Alias
+			aliases[table.Alias] = true
+		}
+	}
+
+	return q
+}
+
+// reports whether a query refers to a table by its name, such as in
+// users.active, so giving the table an alias would break the reference
+func refersTo(q *Query, table string) bool {
+	found := false
+	Inspect(q, func(node interface{}) bool {
+		switch n := node.(type) {
+		case Field:
+			found = found || qualifiedBy(n.Table, n.Name, table) || sqlRefersTo(q, n.Expression, table)
+		case Grouping:
+			found = found || qualifiedBy(n.Table, n.Field, table)
+		case Ordering:
+			found = found || qualifiedBy(n.Table, n.Field, table)
+		case Column:
+			found = found || qualifiedBy(n.Table, n.Name, table)
+		case Predicate:
+			found = found || qualifiedBy(n.Table, n.Field, table)
+		case string:
+			found = found || sqlRefersTo(q, n, table)
+		case Raw:
+			found = found || sqlRefersTo(q, string(n), table)
+		}
+		return !found
+	})
+	return found
+}
+
+// reports whether a reference to a column, given by its table and name or
+// by a dotted name, is qualified by a table
+func qualifiedBy(qualifier, name, table string) bool {
+	if qualifier == "" {
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return false
+		}
+		qualifier = name[:i]
+		qualifier = qualifier[strings.LastIndexByte(qualifier, '.')+1:]
+	}
+	return strings.EqualFold(qualifier, table)
+}
+
+// reports whether raw SQL refers to a table by its name.  SQL that can't be
+// lexed is assumed to.
+func sqlRefersTo(q *Query, sql, table string) bool {
+	if sql == "" {
+		return false
+	}
+	tokens, err := lex(sql, Dialect{IdentifierLeftQuote: q.identifierLeftQuote, IdentifierRightQuote: q.identifierRightQuote})
+	if err != nil {
+		return true
+	}
+	for i := 0; i+1 < len(tokens); i++ {
+		t := tokens[i]
+		if (t.kind == tokenWord || t.kind == tokenQuoted) && strings.EqualFold(t.text, table) && tokens[i+1].isSymbol(".") {
+			return true
+		}
+	}
+	return false
+}
+
+const softDeleteKey = "soft-delete"
+
+// Makes a SELECT query return soft deleted rows as well
+func (q *Query) WithDeleted() *Query {
+	return q.WithValue(softDeleteKey, "with")
+}
+
+// Makes a SELECT query return only the soft deleted rows of the tables in
+// its from clause, joined tables still exclude deleted rows
+func (q *Query) OnlyDeleted() *Query {
+	return q.WithValue(softDeleteKey, "only")
+}
+
+// adds soft delete criteria for registered tables
+func softDelete(q *Query) *Query {
+	mode := q.Value(softDeleteKey)
+	if q.queryType != "SELECT" || mode == "with" {
+		return q
+	}
+
+	for _, from := range q.from {
+		table, ok := lookupTable(from.Table)
+		if from.Subquery != nil || !ok || table.SoftDelete == "" {
+			continue
+		}
+		p := tablePredicate(from.Schema, from.Table, from.Alias, IsNull(table.SoftDelete))
+		if mode == "only" {
+			p.Op = "IS NOT NULL"
+		}
+		q.AndWhere(p)
+	}
+	for i, join := range q.joins {
+		table, ok := lookupTable(join.Table)
+		if !ok || table.SoftDelete == "" {
+			continue
+		}
+		q.addJoinCriteria(i, tablePredicate(join.Schema, join.Table, join.Alias, IsNull(table.SoftDelete)))
+	}
+
+	return q
+}
+
+// qualifies a predicate with the alias of a table, or its schema and name
+// when it has no alias
+func tablePredicate(schema, table, alias string, p Predicate) Predicate {
+	if alias != "" {
+		p.Table = alias
+		return p
+	}
+	p.Schema = schema
+	p.Table = table
+	return p
+}
+
+// adds criteria to the ON criteria of a join with AND logic.  CROSS and
+// NATURAL joins can't have ON criteria, their criteria are added to WHERE.
+func (q *Query) addJoinCriteria(i int, c interface{}) {
+	if !joinHasOn(q.joins[i]) {
+		q.where = andCriteria(q.where, c)
+		return
+	}
+	q.joins[i].On = andCriteria(q.joins[i].On, c)
+}
+
+// reports whether a join takes ON criteria, CROSS and NATURAL joins don't
+func joinHasOn(join Join) bool {
+	joinType := strings.ToUpper(join.Type)
+	return joinType != "CROSS" && !strings.HasPrefix(joinType, "NATURAL")
+}
+
+// returns criteria with more criteria added with AND logic.  Criteria with
+// strings or expressions are wrapped first so an OR in them can't bind the
+// added criteria.
+func andCriteria(c Criteria, e interface{}) Criteria {
+	if len(c.expressions) == 0 {
+		return And(e)
+	} else if c.and && onlyPredicates(c) {
+		c.expressions = append(append([]interface{}(nil), c.expressions...), e)
+		return c
+	}
+	return And(c, e)
+}
+
+// reports whether criteria consist of predicates and nested criteria, which
+// are rendered in parentheses, only
+func onlyPredicates(c Criteria) bool {
+	for _, expression := range c.expressions {
+		switch expression.(type) {
+		case Predicate, Criteria:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_RegisterTable(t *testing.T) {
+	RegisterTable(
+		Table{Name: "users", Schema: "app", Alias: "u", SoftDelete: "deleted_at"},
+		Table{Name: "orders", SoftDelete: "deleted_at"},
+		Table{Name: "roles", Schema: "app"},
+	)
+	defer ResetTables()
+
+	q1 := Select().AddFrom("users").
+		AddJoin(Join{Type: "left", Table: "orders", Alias: "o", On: And("o.user_id = u.id")}, Join{Table: "roles", On: And("roles.id = u.role_id")}).
+		Where("u.active = true")
+
+	tests := []struct {
+		q        *Query
+		expected string
+	}{
+		{q1, "SELECT * FROM app.users u LEFT JOIN orders o ON (o.user_id = u.id) AND o.deleted_at IS NULL JOIN app.roles ON roles.id = u.role_id WHERE (u.active = true) AND (u.deleted_at IS NULL)"},
+		{q1.Clone().WithDeleted(), "SELECT * FROM app.users u LEFT JOIN orders o ON o.user_id = u.id JOIN app.roles ON roles.id = u.role_id WHERE u.active = true"},
+		{q1.Clone().OnlyDeleted(), "SELECT * FROM app.users u LEFT JOIN orders o ON (o.user_id = u.id) AND o.deleted_at IS NULL JOIN app.roles ON roles.id = u.role_id WHERE (u.active = true) AND (u.deleted_at IS NOT NULL)"},
+		{Select().AddFrom(From{Table: "users", Alias: "x"}, "users"), "SELECT * FROM app.users x, app.users u WHERE (x.deleted_at IS NULL) AND (u.deleted_at IS NULL)"},
+		{Select().AddFrom("orders").SkipMiddleware("soft-delete"), "SELECT * FROM orders"},
+		{Select().AddFrom("accounts"), "SELECT * FROM accounts"},
+		{Select().AddFrom("users").Where("users.active"), "SELECT * FROM app.users WHERE (users.active) AND (app.users.deleted_at IS NULL)"},
+		{Select().AddFrom("users").Where(Eq("users.active", true)), "SELECT * FROM app.users WHERE (users.active = ?) AND (app.users.deleted_at IS NULL)"},
+		{Select().AddField(Field{Table: "roles", Name: "name"}).AddFrom("users").AddJoin(Join{Table: "roles", On: And("roles.id = u.role_id")}), "SELECT roles.name FROM app.users u JOIN app.roles ON roles.id = u.role_id WHERE u.deleted_at IS NULL"},
+	}
+	for _, test := range tests {
+		if str := test.q.String(); str != test.expected {
+			t.Errorf("String() returned `%s` expected `%s`", str, test.expected)
+		}
+	}
+	if len(q1.where.expressions) != 1 || len(q1.from[0].Schema) != 0 {
+		t.Error("table middleware modified the original query")
+	}
+}
+
+func Test_Delete(t *testing.T) {
+	RegisterTable(Table{Name: "users", Schema: "app", Alias: "u", SoftDelete: "deleted_at"})
+	defer ResetTables()
+
+	tests := []struct {
+		q        *Query
+		expected string
+	}{
+		{Delete("sessions").Where(Eq("user_id", 1)), "DELETE FROM sessions WHERE user_id = ?"},
+		{Delete(From{Schema: "db1", Table: "sessions"}), "DELETE FROM db1.sessions"},
+		{Delete("users").Where(Eq("id", 1)), "UPDATE app.users SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL"},
+		{Delete("users").Where(Eq("id", 1)).HardDelete(), "DELETE FROM app.users WHERE id = ?"},
+		{Delete("users").SetDialect(PostgreSQL).Where(Eq("id", 1)), `UPDATE "app"."users" SET "deleted_at" = CURRENT_TIMESTAMP WHERE "id" = $1 AND "deleted_at" IS NULL`},
+	}
+	for _, test := range tests {
+		if str, _ := test.q.ToSQL(); str != test.expected {
+			t.Errorf("ToSQL() returned `%s` expected `%s`", str, test.expected)
+		}
+	}
+
+	RegisterTable(Table{Name: "posts", SoftDelete: "deleted_at", SoftDeleteValue: Fn("NOW")})
+	if str, _ := Delete("posts").Where("id = 1").ToSQL(); str != "UPDATE posts SET deleted_at = NOW() WHERE (id = 1) AND deleted_at IS NULL" {
+		t.Errorf("ToSQL() returned `%s` with SoftDeleteValue", str)
+	}
+
+	expected := "UPDATE posts SET deleted_at = NOW() WHERE (id = 1 OR id = 2) AND deleted_at IS NULL"
+	if str, _ := Delete("posts").Where(And("id = 1 OR id = 2")).ToSQL(); str != expected {
+		t.Errorf("ToSQL() returned `%s` expected `%s`", str, expected)
+	}
+}
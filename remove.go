@@ -0,0 +1,248 @@
+package squiggle
+
+// Remove fields from a query.  A field is removed when its alias or name
+// matches one of the arguments.
+//
+// 	squiggle.Select().AddField("id", "username").RemoveField("id")
+// 	// => SELECT username
+func (q *Query) RemoveField(names ...string) *Query {
+	q = q.builder()
+	var fields []Field
+	for _, field := range q.fields {
+		if !matches(names, field.Alias, field.Name) {
+			fields = append(fields, field)
+		}
+	}
+	q.fields = fields
+
+	return q
+}
+
+// Replace the fields whose alias or name matches the name with a new field
+//
+// 	squiggle.Select().AddField("id").ReplaceField("id", squiggle.Field{Expression: "COUNT(*)"})
+// 	// => SELECT COUNT(*)
+func (q *Query) ReplaceField(name string, field Field) *Query {
+	q = q.builder()
+	for i := range q.fields {
+		if matches([]string{name}, q.fields[i].Alias, q.fields[i].Name) {
+			q.fields[i] = field
+		}
+	}
+
+	return q
+}
+
+// Remove all fields from a query
+func (q *Query) ClearFields() *Query {
+	q = q.builder()
+	q.fields = nil
+
+	return q
+}
+
+// Remove tables from the from clause of a query.  A table is removed when its
+// alias or table name matches one of the arguments.
+func (q *Query) RemoveFrom(tables ...string) *Query {
+	q = q.builder()
+	var froms []From
+	for _, from := range q.from {
+		if !matches(tables, from.Alias, from.Table) {
+			froms = append(froms, from)
+		}
+	}
+	q.from = froms
+
+	return q
+}
+
+// Replace the tables of the from clause whose alias or table name matches
+// the table with a new one
+func (q *Query) ReplaceFrom(table string, from From) *Query {
+	q = q.builder()
+	for i := range q.from {
+		if matches([]string{table}, q.from[i].Alias, q.from[i].Table) {
+			q.from[i] = from
+		}
+	}
+
+	return q
+}
+
+// Remove all tables from the from clause of a query
+func (q *Query) ClearFrom() *Query {
+	q = q.builder()
+	q.from = nil
+
+	return q
+}
+
+// Remove joins from a query.  A join is removed when its alias or table name
+// matches one of the arguments.
+func (q *Query) RemoveJoin(tables ...string) *Query {
+	q = q.builder()
+	var joins []Join
+	for _, join := range q.joins {
+		if !matches(tables, join.Alias, join.Table) {
+			joins = append(joins, join)
+		}
+	}
+	q.joins = joins
+
+	return q
+}
+
+// Replace the joins whose alias or table name matches the table with a new
+// join
+//
+// 	squiggle.Select().
+// 		AddJoin(squiggle.Join{Type: "inner", Table: "roles", Alias: "r", On: squiggle.And("r.id = role_id")}).
+// 		ReplaceJoin("r", squiggle.Join{Type: "left", Table: "roles", Alias: "r", On: squiggle.And("r.id = role_id")})
+// 	// => SELECT * LEFT JOIN roles r ON r.id = role_id
+func (q *Query) ReplaceJoin(table string, join Join) *Query {
+	q = q.builder()
+	for i := range q.joins {
+		if matches([]string{table}, q.joins[i].Alias, q.joins[i].Table) {
+			q.joins[i] = join
+		}
+	}
+
+	return q
+}
+
+// Remove all joins from a query
+func (q *Query) ClearJoins() *Query {
+	q = q.builder()
+	q.joins = nil
+
+	return q
+}
+
+// Remove groupings from a query by field name
+func (q *Query) RemoveGrouping(fields ...string) *Query {
+	q = q.builder()
+	var groupings []Grouping
+	for _, grouping := range q.groupings {
+		if !matches(fields, grouping.Field) {
+			groupings = append(groupings, grouping)
+		}
+	}
+	q.groupings = groupings
+
+	return q
+}
+
+// Replace the groupings of a field with a new grouping
+func (q *Query) ReplaceGrouping(field string, grouping Grouping) *Query {
+	q = q.builder()
+	for i := range q.groupings {
+		if q.groupings[i].Field == field {
+			q.groupings[i] = grouping
+		}
+	}
+
+	return q
+}
+
+// Remove all groupings from a query
+func (q *Query) ClearGroupings() *Query {
+	q = q.builder()
+	q.groupings = nil
+
+	return q
+}
+
+// Remove orderings from a query by field name
+func (q *Query) RemoveOrdering(fields ...string) *Query {
+	q = q.builder()
+	var orderings []Ordering
+	for _, ordering := range q.orderings {
+		if !matches(fields, ordering.Field) {
+			orderings = append(orderings, ordering)
+		}
+	}
+	q.orderings = orderings
+
+	return q
+}
+
+// Replace the orderings of a field with a new ordering
+//
+// 	squiggle.Select().AddOrdering("id").ReplaceOrdering("id", squiggle.Ordering{Field: "id", Desc: true})
+// 	// => SELECT * ORDER BY id DESC
+func (q *Query) ReplaceOrdering(field string, ordering Ordering) *Query {
+	q = q.builder()
+	for i := range q.orderings {
+		if q.orderings[i].Field == field {
+			q.orderings[i] = ordering
+		}
+	}
+
+	return q
+}
+
+// Remove all orderings from a query
+//
+// 	squiggle.Select().AddFrom("users").AddOrdering("id").ClearOrderings()
+// 	// => SELECT * FROM users
+func (q *Query) ClearOrderings() *Query {
+	q = q.builder()
+	q.orderings = nil
+
+	return q
+}
+
+// Remove all criteria from the "where" portion of a query
+func (q *Query) ClearWhere() *Query {
+	q = q.builder()
+	q.where = Criteria{}
+
+	return q
+}
+
+// Remove all criteria from the "having" portion of a query
+func (q *Query) ClearHaving() *Query {
+	q = q.builder()
+	q.having = Criteria{}
+
+	return q
+}
+
+// Remove DISTINCT from a query
+func (q *Query) ResetDistinct() *Query {
+	q = q.builder()
+	q.distinct = false
+
+	return q
+}
+
+// Remove the limit from a query
+func (q *Query) ResetLimit() *Query {
+	q = q.builder()
+	q.limit = 0
+
+	return q
+}
+
+// Remove the offset from a query
+func (q *Query) ResetOffset() *Query {
+	q = q.builder()
+	q.offset = 0
+
+	return q
+}
+
+// reports whether any of the non-empty values is one of the names
+func matches(names []string, values ...string) bool {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		for _, name := range names {
+			if name == value {
+				return true
+			}
+		}
+	}
+	return false
+}
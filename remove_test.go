@@ -0,0 +1,39 @@
+package squiggle
+
+import (
+	"testing"
+)
+
+func Test_RemoveAndReplace(t *testing.T) {
+	q1 := Select().
+		AddField("id", Field{Name: "username", Alias: "name"}, "email").
+		AddFrom("users", From{Table: "accounts", Alias: "a"}).
+		AddJoin(Join{Type: "inner", Table: "roles", Alias: "r", On: And("r.id = role_id")}, Join{Type: "inner", Table: "teams", On: And("teams.id = team_id")}).
+		AddGrouping("id", "email").
+		AddOrdering("id", "email").
+		Where("a = 1").
+		Having("COUNT(*) > 1").
+		Limit(10).
+		Offset(20)
+
+	q1.RemoveField("name", "email").
+		ReplaceField("id", Field{Expression: "COUNT(*)"}).
+		RemoveFrom("a").
+		RemoveJoin("teams").
+		ReplaceJoin("r", Join{Type: "left", Table: "roles", Alias: "r", On: And("r.id = role_id")}).
+		RemoveGrouping("email").
+		ReplaceOrdering("id", Ordering{Field: "id", Desc: true}).
+		RemoveOrdering("email").
+		ClearHaving().
+		ResetOffset()
+
+	expected := "SELECT COUNT(*) FROM users LEFT JOIN roles r ON r.id = role_id WHERE a = 1 GROUP BY id ORDER BY id DESC LIMIT 10"
+	if str := q1.String(); str != expected {
+		t.Errorf("String() returned `%s` expected `%s`", str, expected)
+	}
+
+	q1.ClearFields().ClearFrom().ClearJoins().ClearWhere().ClearGroupings().ClearOrderings().ResetLimit()
+	if str := q1.String(); str != "SELECT *" {
+		t.Errorf("String() returned `%s` expected `SELECT *`", str)
+	}
+}
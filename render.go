@@ -0,0 +1,150 @@
+package squiggle
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// the buffer a query is rendered into: its SQL and the values bound to its
+// placeholders.  Every part of a query, its expressions and subqueries write
+// to the same buffer so rendering doesn't build intermediate strings.
+type sqlWriter struct {
+	buf  []byte
+	args []interface{}
+}
+
+// appends a string to the SQL
+func (w *sqlWriter) write(s string) {
+	w.buf = append(w.buf, s...)
+}
+
+// returns the SQL written so far
+func (w *sqlWriter) String() string {
+	return string(w.buf)
+}
+
+// Appends the SQL of the query to dst and returns the extended buffer along
+// with the values bound to its placeholders.  Reusing a buffer across
+// queries avoids allocating one for every query.
+//
+// 	buf, args := q.AppendSQL(buf[:0])
+// 	db.Exec(string(buf), args...)
+func (q *Query) AppendSQL(dst []byte) ([]byte, []interface{}) {
+	w := &sqlWriter{buf: dst}
+	q.writeSQL(w, " ")
+	return w.buf, w.args
+}
+
+// Writes the SQL of the query to w, implementing io.WriterTo.  The values
+// bound to its placeholders aren't written, use AppendSQL() or ToSQL() when
+// they're needed.
+//
+// 	squiggle.Select().AddFrom("users").WriteTo(os.Stdout)
+func (q *Query) WriteTo(w io.Writer) (int64, error) {
+	sw := &sqlWriter{}
+	q.writeSQL(sw, " ")
+	n, err := w.Write(sw.buf)
+	return int64(n), err
+}
+
+// renders the query like ToSQL(), returning the error rendering panics with
+// such as a *TenantError instead of panicking
+func (q *Query) trySQL() (sql string, args []interface{}, err error) {
+	defer recoverError(&err)
+	sql, args = q.ToSQL()
+	return sql, args, nil
+}
+
+// writes the SQL of the query with the comment holding its tags, separated
+// from the SQL by sep.  Middleware is applied once, then queries that
+// validate strictly are validated.
+func (q *Query) writeSQL(w *sqlWriter, sep string) {
+	q = q.applyMiddleware()
+	q.mustValidate()
+	comment := q.comment()
+	if comment != "" && q.commentPosition == CommentStart {
+		w.write(comment)
+		w.write(sep)
+	}
+	q.toSQL(w)
+	if comment != "" && q.commentPosition != CommentStart {
+		w.write(sep)
+		w.write(comment)
+	}
+}
+
+// writes an identifier quoted with the identifier quotes of the query.
+// Every part of a dotted name is quoted on its own and quote characters in
+// the identifier are escaped by doubling the right quote.  A * is never
+// quoted.  In strict mode identifiers are checked first, see
+// StrictIdentifiers().  Without identifier quotes, the default, identifiers
+// are written as they are so names such as COUNT(*) keep working, nothing is
+// escaped then and identifiers from user input must be checked with strict
+// mode or CheckIdentifier().
+func (q *Query) writeIdentifier(w *sqlWriter, identifier string) {
+	for {
+		i := strings.IndexByte(identifier, '.')
+		if i < 0 {
+			q.writePart(w, identifier)
+			return
+		}
+		q.writePart(w, identifier[:i])
+		w.write(".")
+		identifier = identifier[i+1:]
+	}
+}
+
+// returns an identifier quoted with the identifier quotes of the query, see
+// writeIdentifier()
+func (q *Query) identfierQuote(identifier string) string {
+	w := &sqlWriter{}
+	q.writeIdentifier(w, identifier)
+	return w.String()
+}
+
+// writes one part of an identifier quoted with the identifier quotes of the
+// query.  Without quotes the part is written unchecked unless the query is in
+// strict mode.
+func (q *Query) writePart(w *sqlWriter, part string) {
+	if q.strictIdentifiers {
+		if err := checkIdentifierPart(part, q.allowedIdentifiers); err != nil {
+			panic(err)
+		}
+	}
+	if part == "*" || (q.identifierLeftQuote == "" && q.identifierRightQuote == "") {
+		w.write(part)
+		return
+	}
+
+	w.write(q.identifierLeftQuote)
+	if q.identifierRightQuote != "" && strings.Contains(part, q.identifierRightQuote) {
+		part = strings.Replace(part, q.identifierRightQuote, q.identifierRightQuote+q.identifierRightQuote, -1)
+	}
+	w.write(part)
+	w.write(q.identifierRightQuote)
+}
+
+// writes a name qualified by a table and schema when they're set
+func (q *Query) writeQualified(w *sqlWriter, schema, table, name string) {
+	if schema != "" {
+		q.writeIdentifier(w, schema)
+		w.write(".")
+	}
+	if table != "" {
+		q.writeIdentifier(w, table)
+		w.write(".")
+	}
+	q.writeIdentifier(w, name)
+}
+
+// appends a value to the args and writes the placeholder that refers to it
+func (q *Query) bind(w *sqlWriter, value interface{}) {
+	w.args = append(w.args, value)
+	if q.placeholder == "" || q.placeholder == "?" {
+		w.write("?")
+		return
+	}
+	w.write(q.placeholder)
+	w.buf = strconv.AppendInt(w.buf, int64(len(w.args)), 10)
+}
@@ -0,0 +1,85 @@
+package squiggle
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func Test_AppendSQL(t *testing.T) {
+	q1 := Select().AddFrom("users").Where(Eq("id", 1)).SetPlaceholder("$")
+	q2 := Select().AddFrom(From{Subquery: q1, Alias: "u"}).Where(In("u.status", []string{"a", "b"})).SetPlaceholder("$")
+
+	buf := []byte("-- query\n")
+	buf, args := q2.AppendSQL(buf)
+	expected := "-- query\nSELECT * FROM (SELECT * FROM users WHERE id = $1) u WHERE u.status IN ($2, $3)"
+	if string(buf) != expected {
+		t.Errorf("AppendSQL() returned `%s` expected `%s`", buf, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "a", "b"}) {
+		t.Errorf("AppendSQL() returned the args %v", args)
+	}
+
+	buf, args = q1.Tag("app", "x").AppendSQL(buf[:0])
+	expected = "SELECT * FROM users WHERE id = $1 /*app='x'*/"
+	if string(buf) != expected || len(args) != 1 {
+		t.Errorf("AppendSQL() returned `%s` expected `%s`", buf, expected)
+	}
+}
+
+func Test_WriteTo(t *testing.T) {
+	q := Select().AddField("id").AddFrom("users").Where(Eq("id", 1)).Tag("app", "x").CommentAt(CommentStart)
+	var buf bytes.Buffer
+	n, err := q.WriteTo(&buf)
+	expected := "/*app='x'*/ SELECT id FROM users WHERE id = ?"
+	if err != nil || buf.String() != expected || n != int64(len(expected)) {
+		t.Errorf("WriteTo() wrote `%s` (%d, %v) expected `%s`", buf.String(), n, err, expected)
+	}
+}
+
+// queries representative of an API: a lookup by key, a join with a mix of
+// criteria and a large IN list
+func benchmarkQueries() []struct {
+	name string
+	q    *Query
+} {
+	ids := make([]int, 1000)
+	for i := range ids {
+		ids[i] = i
+	}
+
+	return []struct {
+		name string
+		q    *Query
+	}{
+		{"Simple", Select().AddField("id", "name").AddFrom("users").Where(Eq("id", 1))},
+		{"Join", Select().AddField("u.id", "o.total").AddFrom(From{Table: "users", Alias: "u"}).
+			AddJoin(Join{Table: "orders", Alias: "o", On: And("o.user_id = u.id")}).
+			Where(And(Eq("u.status", "active"), Or(Gt("o.total", 100), IsNull("o.total")))).
+			AddGrouping("u.id").AddOrdering("u.id").Limit(10).SetPlaceholder("$")},
+		{"InList", Select().AddFrom("users").Where(In("id", ids)).SetPlaceholder("$")},
+	}
+}
+
+func BenchmarkToSQL(b *testing.B) {
+	for _, bench := range benchmarkQueries() {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bench.q.ToSQL()
+			}
+		})
+	}
+}
+
+func BenchmarkAppendSQL(b *testing.B) {
+	for _, bench := range benchmarkQueries() {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var buf []byte
+			for i := 0; i < b.N; i++ {
+				buf, _ = bench.q.AppendSQL(buf[:0])
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package squiggle
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// The methods of a database a Runner uses.  *sql.DB, *sql.Tx and *sql.Conn
+// all have them.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// A QueryEvent describes the execution of a query to hooks.  Duration,
+// RowsAffected and Err are set before the After hooks are called.  When the
+// query can't be rendered or is missing values for its slots Err is set
+// before the Before hooks are called already, the query isn't executed then
+// and SQL is empty when rendering failed.
+type QueryEvent struct {
+	Query    *Query
+	SQL      string
+	Args     []interface{}
+	Start    time.Time
+	Duration time.Duration
+	// the rows affected by Exec(), -1 for Query() and when the driver
+	// doesn't report it
+	RowsAffected int64
+	Err          error
+}
+
+// A Hook is called before and after a Runner executes a query.  The context
+// Before returns is the one the query is executed with and After is called
+// with, so hooks can pass values such as tracing spans along.
+type Hook interface {
+	Before(ctx context.Context, e *QueryEvent) context.Context
+	After(ctx context.Context, e *QueryEvent)
+}
+
+// HookFuncs turns functions into a Hook, either function may be nil
+//
+// 	squiggle.HookFuncs{AfterFunc: func(ctx context.Context, e *squiggle.QueryEvent) {
+// 		queryDuration.WithLabelValues(e.Query.Type()).Observe(e.Duration.Seconds())
+// 	}}
+type HookFuncs struct {
+	BeforeFunc func(ctx context.Context, e *QueryEvent) context.Context
+	AfterFunc  func(ctx context.Context, e *QueryEvent)
+}
+
+func (h HookFuncs) Before(ctx context.Context, e *QueryEvent) context.Context {
+	if h.BeforeFunc == nil {
+		return ctx
+	}
+	return h.BeforeFunc(ctx, e)
+}
+
+func (h HookFuncs) After(ctx context.Context, e *QueryEvent) {
+	if h.AfterFunc != nil {
+		h.AfterFunc(ctx, e)
+	}
+}
+
+// A Runner executes queries on a database calling hooks around every
+// execution
+type Runner struct {
+	db    DB
+	hooks []Hook
+}
+
+// Creates a runner executing queries on a database
+//
+// 	runner := squiggle.NewRunner(db, squiggle.SlogHook(slog.Default(), squiggle.SlogOptions{}))
+// 	rows, err := runner.Query(ctx, squiggle.Select().AddFrom("users"))
+func NewRunner(db DB, hooks ...Hook) *Runner {
+	return &Runner{db: db, hooks: hooks}
+}
+
+// Adds hooks to the runner.  Before hooks are called in the order they were
+// added, After hooks in the reverse order.
+func (r *Runner) AddHook(hooks ...Hook) *Runner {
+	r.hooks = append(r.hooks, hooks...)
+	return r
+}
+
+// Executes a query that doesn't return rows such as an INSERT or DELETE
+func (r *Runner) Exec(ctx context.Context, q *Query) (sql.Result, error) {
+	ctx, e, err := r.before(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return r.exec(ctx, e)
+}
+
+// Executes a query that returns rows such as a SELECT
+func (r *Runner) Query(ctx context.Context, q *Query) (*sql.Rows, error) {
+	ctx, e, err := r.before(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return r.query(ctx, e)
+}
+
+// Executes a compiled query that doesn't return rows with the values of its
+// slots, see Compile()
+//
+// 	deleteUser := squiggle.Delete("users").Where(squiggle.Eq("id", squiggle.Slot("id"))).Compile()
+// 	runner.ExecTemplate(ctx, deleteUser, map[string]interface{}{"id": 1})
+func (r *Runner) ExecTemplate(ctx context.Context, t *Template, values map[string]interface{}) (sql.Result, error) {
+	ctx, e, err := r.beforeTemplate(ctx, t, values)
+	if err != nil {
+		return nil, err
+	}
+	return r.exec(ctx, e)
+}
+
+// Executes a compiled query that returns rows with the values of its slots,
+// see Compile()
+func (r *Runner) QueryTemplate(ctx context.Context, t *Template, values map[string]interface{}) (*sql.Rows, error) {
+	ctx, e, err := r.beforeTemplate(ctx, t, values)
+	if err != nil {
+		return nil, err
+	}
+	return r.query(ctx, e)
+}
+
+// executes the query of an event with ExecContext
+func (r *Runner) exec(ctx context.Context, e *QueryEvent) (sql.Result, error) {
+	result, err := r.db.ExecContext(ctx, e.SQL, e.Args...)
+	if err == nil {
+		if n, err := result.RowsAffected(); err == nil {
+			e.RowsAffected = n
+		}
+	}
+	r.after(ctx, e, err)
+
+	return result, err
+}
+
+// executes the query of an event with QueryContext
+func (r *Runner) query(ctx context.Context, e *QueryEvent) (*sql.Rows, error) {
+	rows, err := r.db.QueryContext(ctx, e.SQL, e.Args...)
+	r.after(ctx, e, err)
+
+	return rows, err
+}
+
+// renders a query tagged with the tags of the context and calls the
+// Before hooks.  Errors rendering panics with, such as a *TenantError, and
+// slots without values are returned without executing the query.
+func (r *Runner) before(ctx context.Context, q *Query) (context.Context, *QueryEvent, error) {
+	q = q.withDefaultTags(TagsFromContext(ctx))
+	sql, args, err := q.trySQL()
+	if err == nil {
+		err = unfilledSlot(args)
+	}
+	e := &QueryEvent{Query: q, SQL: sql, Args: args, RowsAffected: -1}
+	if err != nil {
+		return ctx, nil, r.fail(ctx, e, err)
+	}
+	ctx, e = r.start(ctx, e)
+	return ctx, e, nil
+}
+
+// fills the slots of a template, tags it with the tags of the context and
+// calls the Before hooks
+func (r *Runner) beforeTemplate(ctx context.Context, t *Template, values map[string]interface{}) (context.Context, *QueryEvent, error) {
+	args, err := t.Args(values)
+	q, sql := t.tagged(TagsFromContext(ctx))
+	e := &QueryEvent{Query: q, SQL: sql, Args: args, RowsAffected: -1}
+	if err != nil {
+		return ctx, nil, r.fail(ctx, e, err)
+	}
+	ctx, e = r.start(ctx, e)
+	return ctx, e, nil
+}
+
+// calls the hooks for a query that isn't executed because of an error and
+// returns the error
+func (r *Runner) fail(ctx context.Context, e *QueryEvent, err error) error {
+	e.Err = err
+	ctx, e = r.start(ctx, e)
+	r.after(ctx, e, err)
+	return err
+}
+
+// calls the Before hooks and starts the clock of an event
+func (r *Runner) start(ctx context.Context, e *QueryEvent) (context.Context, *QueryEvent) {
+	for _, hook := range r.hooks {
+		ctx = hook.Before(ctx, e)
+	}
+	e.Start = time.Now()
+
+	return ctx, e
+}
+
+// completes the event and calls the After hooks
+func (r *Runner) after(ctx context.Context, e *QueryEvent, err error) {
+	e.Duration = time.Since(e.Start)
+	e.Err = err
+	for i := len(r.hooks) - 1; i >= 0; i-- {
+		r.hooks[i].After(ctx, e)
+	}
+}
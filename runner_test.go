@@ -0,0 +1,201 @@
+package squiggle
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// a database/sql driver recording the queries it's given and the
+// statements it prepares.  Queries containing "fail" return an error.
+type fakeDriver struct {
+	queries  []string
+	prepared []string
+	closed   []string
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+type fakeResult int64
+
+type fakeRows struct {
+	done bool
+}
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register("squiggle-fake", fake)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.driver.prepared = append(c.driver.prepared, query)
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.queries = append(c.driver.queries, query)
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("query failed")
+	}
+	return fakeResult(len(args)), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.queries = append(c.driver.queries, query)
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("query failed")
+	}
+	return &fakeRows{}, nil
+}
+
+func (s *fakeStmt) Close() error {
+	s.conn.driver.closed = append(s.conn.driver.closed, s.query)
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("Exec() is not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("Query() is not supported")
+}
+
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func Test_Runner(t *testing.T) {
+	db, err := sql.Open("squiggle-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	fake.queries = nil
+
+	var calls []string
+	var events []QueryEvent
+	hook := func(name string) Hook {
+		return HookFuncs{
+			BeforeFunc: func(ctx context.Context, e *QueryEvent) context.Context {
+				calls = append(calls, "before "+name)
+				return ctx
+			},
+			AfterFunc: func(ctx context.Context, e *QueryEvent) {
+				calls = append(calls, "after "+name)
+				events = append(events, *e)
+			},
+		}
+	}
+	runner := NewRunner(db, hook("a")).AddHook(hook("b"))
+	ctx := context.Background()
+
+	result, err := runner.Exec(ctx, Delete("users").Where(In("id", []int{1, 2})))
+	if err != nil {
+		t.Fatalf("Exec() returned error `%s`", err)
+	}
+	if n, _ := result.RowsAffected(); n != 2 {
+		t.Errorf("Exec() returned %d rows affected", n)
+	}
+	rows, err := runner.Query(ctx, Select().AddFrom("users"))
+	if err != nil {
+		t.Fatalf("Query() returned error `%s`", err)
+	}
+	rows.Close()
+	if _, err := runner.Query(ctx, Select().AddFrom("fail")); err == nil {
+		t.Error("Query() should return the error of the database")
+	}
+
+	expectedCalls := []string{"before a", "before b", "after b", "after a", "before a", "before b", "after b", "after a", "before a", "before b", "after b", "after a"}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("hooks were called %v expected %v", calls, expectedCalls)
+	}
+	expectedQueries := []string{"DELETE FROM users WHERE id IN (?, ?)", "SELECT * FROM users", "SELECT * FROM fail"}
+	if !reflect.DeepEqual(fake.queries, expectedQueries) {
+		t.Errorf("database received %v expected %v", fake.queries, expectedQueries)
+	}
+
+	if e := events[0]; e.SQL != expectedQueries[0] || !reflect.DeepEqual(e.Args, []interface{}{1, 2}) || e.RowsAffected != 2 || e.Err != nil || e.Start.IsZero() {
+		t.Errorf("unexpected event for Exec() %+v", e)
+	}
+	if e := events[4]; e.RowsAffected != -1 || e.Err == nil {
+		t.Errorf("unexpected event for a failed Query() %+v", e)
+	}
+
+	Use(TenantScope("tenant_id", "users"))
+	defer ResetMiddleware()
+	if _, err := runner.Query(ctx, Select().AddFrom("users")); err == nil {
+		t.Error("Query() should return the error of rendering the query")
+	} else if _, ok := err.(*TenantError); !ok {
+		t.Errorf("Query() returned error %v expected a *TenantError", err)
+	}
+	if len(fake.queries) != 3 {
+		t.Error("Query() should not execute a query that can't be rendered")
+	}
+	if len(calls) != 16 || calls[12] != "before a" || calls[15] != "after a" {
+		t.Errorf("hooks were called %v for a query that can't be rendered", calls[12:])
+	}
+	if e := events[len(events)-1]; e.SQL != "" || e.Start.IsZero() {
+		t.Errorf("unexpected event for a query that can't be rendered %+v", e)
+	} else if _, ok := e.Err.(*TenantError); !ok {
+		t.Errorf("event for a query that can't be rendered has error %v expected a *TenantError", e.Err)
+	}
+}
@@ -0,0 +1,155 @@
+package squiggle
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// The method of a database a StmtCache prepares statements with.  *sql.DB
+// and *sql.Conn have it.
+type Preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// A StmtCache executes queries with prepared statements, preparing every
+// SQL text once and reusing its statement afterwards.  It's a DB so a Runner
+// can execute queries and templates with it.  When the cache is full the
+// least recently used statement is closed, statements still in use are
+// closed once they're done.  Use Tx.StmtContext() to use the cached
+// statements of a *sql.DB in a transaction.
+//
+// 	cache := squiggle.NewStmtCache(db, 100)
+// 	defer cache.Close()
+// 	runner := squiggle.NewRunner(cache)
+type StmtCache struct {
+	db   Preparer
+	size int
+
+	mu    sync.Mutex
+	stmts map[string]*list.Element
+	// the SQL of the cached statements, most recently used first
+	lru *list.List
+}
+
+// a cached statement and the number of queries executing it
+type cachedStmt struct {
+	sql     string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// Creates a cache of up to size prepared statements, a size of 0 or less
+// doesn't limit the cache
+func NewStmtCache(db Preparer, size int) *StmtCache {
+	return &StmtCache{db: db, size: size, stmts: map[string]*list.Element{}, lru: list.New()}
+}
+
+// returns the cached statement of an SQL text, preparing it unless it's
+// cached.  The statement isn't closed before it's released.
+func (c *StmtCache) acquire(ctx context.Context, query string) (*cachedStmt, error) {
+	c.mu.Lock()
+	if e, ok := c.stmts[query]; ok {
+		c.lru.MoveToFront(e)
+		cached := e.Value.(*cachedStmt)
+		cached.refs++
+		c.mu.Unlock()
+		return cached, nil
+	}
+	c.mu.Unlock()
+
+	// the lock isn't held while preparing so a slow prepare doesn't block
+	// other queries
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.stmts[query]; ok {
+		// prepared concurrently, keep the cached one
+		stmt.Close()
+		c.lru.MoveToFront(e)
+		cached := e.Value.(*cachedStmt)
+		cached.refs++
+		return cached, nil
+	}
+	cached := &cachedStmt{sql: query, stmt: stmt, refs: 1}
+	c.stmts[query] = c.lru.PushFront(cached)
+	for c.size > 0 && c.lru.Len() > c.size {
+		c.evict(c.lru.Back())
+	}
+
+	return cached, nil
+}
+
+// releases a statement returned by acquire(), closing it when it was
+// evicted in the meantime
+func (c *StmtCache) release(cached *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached.refs--
+	if cached.evicted && cached.refs == 0 {
+		cached.stmt.Close()
+	}
+}
+
+// removes a statement from the cache, it's closed once no query executes it.
+// The lock must be held.
+func (c *StmtCache) evict(e *list.Element) error {
+	cached := c.lru.Remove(e).(*cachedStmt)
+	delete(c.stmts, cached.sql)
+	cached.evicted = true
+	if cached.refs == 0 {
+		return cached.stmt.Close()
+	}
+	return nil
+}
+
+// Returns the number of cached statements
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Executes a query that doesn't return rows with its prepared statement
+func (c *StmtCache) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	cached, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer c.release(cached)
+	return cached.stmt.ExecContext(ctx, args...)
+}
+
+// Executes a query that returns rows with its prepared statement.  The rows
+// keep the statement open until they're closed.
+func (c *StmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	cached, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer c.release(cached)
+	return cached.stmt.QueryContext(ctx, args...)
+}
+
+// Closes every cached statement that no query executes and empties the
+// cache, returning the first error.  Statements still executing are closed
+// when they're done.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var first error
+	for c.lru.Len() > 0 {
+		if err := c.evict(c.lru.Front()); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
@@ -0,0 +1,45 @@
+package squiggle
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func Test_StmtCache(t *testing.T) {
+	db, _ := sql.Open("squiggle-fake", "")
+	defer db.Close()
+	fake.queries, fake.prepared, fake.closed = nil, nil, nil
+
+	cache := NewStmtCache(db, 2)
+	runner := NewRunner(cache)
+	ctx := context.Background()
+	byID := Select().AddFrom("users").Where(Eq("id", Slot("id"))).Compile()
+
+	for i := 0; i < 3; i++ {
+		rows, err := runner.QueryTemplate(ctx, byID, map[string]interface{}{"id": i})
+		if err != nil {
+			t.Fatalf("QueryTemplate() returned error `%s`", err)
+		}
+		rows.Close()
+	}
+	runner.Exec(ctx, Delete("users").Where(Eq("id", 1)))
+	runner.Exec(ctx, Delete("orders").Where(Eq("id", 1)))
+	runner.Exec(ctx, Delete("users").Where(Eq("id", 2)))
+
+	expected := []string{"SELECT * FROM users WHERE id = ?", "DELETE FROM users WHERE id = ?", "DELETE FROM orders WHERE id = ?"}
+	if !reflect.DeepEqual(fake.prepared, expected) {
+		t.Errorf("prepared %v expected %v", fake.prepared, expected)
+	}
+	if len(fake.queries) != 6 {
+		t.Errorf("database received %v", fake.queries)
+	}
+	if cache.Len() != 2 || !reflect.DeepEqual(fake.closed, expected[:1]) {
+		t.Errorf("the least recently used statement should be closed, closed %v", fake.closed)
+	}
+
+	if err := cache.Close(); err != nil || cache.Len() != 0 || len(fake.closed) != 3 {
+		t.Errorf("Close() closed %v, %v", fake.closed, err)
+	}
+}
@@ -0,0 +1,145 @@
+package squiggle
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// a column derived from a struct field tagged with `db:"..."`
+type structColumn struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	readOnly  bool
+	pk        bool
+	tagged    bool
+}
+
+// the columns of a struct type in field order
+type structInfo struct {
+	columns []structColumn
+}
+
+// reflection metadata is cached per type so it's only computed once
+var structInfoCache sync.Map
+
+// returns the column metadata for a struct type.  The tag format is
+// `db:"name,omitempty,readonly,pk"`.  Fields tagged `db:"-"` and unexported
+// fields are skipped, fields without a tag use the Go field name and
+// anonymous struct fields are flattened into the parent.
+func getStructInfo(t reflect.Type) *structInfo {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+
+	info := &structInfo{columns: structColumns(t, nil)}
+	actual, _ := structInfoCache.LoadOrStore(t, info)
+	return actual.(*structInfo)
+}
+
+func structColumns(t reflect.Type, parentIndex []int) []structColumn {
+	var columns []structColumn
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+
+		index := make([]int, len(parentIndex)+1)
+		copy(index, parentIndex)
+		index[len(parentIndex)] = i
+
+		if sf.Anonymous && !hasTag {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				columns = append(columns, structColumns(ft, index)...)
+				continue
+			}
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		column := structColumn{name: parts[0], index: index, tagged: hasTag}
+		if column.name == "" {
+			column.name = sf.Name
+		}
+		for _, option := range parts[1:] {
+			switch option {
+			case "omitempty":
+				column.omitEmpty = true
+			case "readonly":
+				column.readOnly = true
+			case "pk":
+				column.pk = true
+			}
+		}
+		columns = append(columns, column)
+	}
+
+	return columns
+}
+
+// returns the struct value held by v dereferencing pointers, ok is false when
+// v doesn't hold a struct
+func structValue(v interface{}) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return rv, false
+		}
+		rv = rv.Elem()
+	}
+	return rv, rv.Kind() == reflect.Struct
+}
+
+// returns the value of a column in a struct value.  ok is false when the
+// column is inside a nil embedded pointer.
+func (c structColumn) value(rv reflect.Value) (reflect.Value, bool) {
+	for i, x := range c.index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return rv, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// Add fields to a select query derived from the `db` tags of one or more
+// structs (or pointers to structs).  Fields without a tag use the name of the
+// struct field and fields tagged `db:"-"` are skipped.
+//
+// 	type User struct {
+// 		ID       int    `db:"id,pk"`
+// 		Username string `db:"username"`
+// 		Secret   string `db:"-"`
+// 	}
+// 	squiggle.Select().AddFieldsFrom(User{}).AddFrom("users")
+// 	// => SELECT id, username FROM users
+func (q *Query) AddFieldsFrom(structs ...interface{}) *Query {
+	q = q.builder()
+	for _, s := range structs {
+		t := reflect.TypeOf(s)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("unexpected type %T used in AddFieldsFrom()", s))
+		}
+		for _, column := range getStructInfo(t).columns {
+			q.fields = append(q.fields, Field{Name: column.name})
+		}
+	}
+
+	return q
+}
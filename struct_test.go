@@ -0,0 +1,50 @@
+package squiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testTimestamps struct {
+	CreatedAt string `db:"created_at,readonly"`
+}
+
+type testUser struct {
+	ID       int    `db:"id,pk"`
+	Username string `db:"username"`
+	Email    string `db:"email,omitempty"`
+	Password string `db:"-"`
+	Nickname string
+	secret   string
+	testTimestamps
+}
+
+func Test_getStructInfo(t *testing.T) {
+	info := getStructInfo(reflect.TypeOf(testUser{}))
+
+	var names []string
+	for _, column := range info.columns {
+		names = append(names, column.name)
+	}
+	expected := []string{"id", "username", "email", "Nickname", "created_at"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("getStructInfo() returned columns %v expected %v", names, expected)
+	}
+	if !info.columns[0].pk || !info.columns[2].omitEmpty || !info.columns[4].readOnly {
+		t.Error("getStructInfo() did not parse tag options")
+	}
+
+	if getStructInfo(reflect.TypeOf(testUser{})) != info {
+		t.Error("getStructInfo() did not cache struct metadata")
+	}
+}
+
+func Test_AddFieldsFrom(t *testing.T) {
+	q1 := Select().AddFieldsFrom(&testUser{}).AddFrom("users")
+
+	str := q1.String()
+	expected := "SELECT id, username, email, Nickname, created_at FROM users"
+	if str != expected {
+		t.Errorf("AddFieldsFrom() returned `%s` expected `%s`", str, expected)
+	}
+}
@@ -0,0 +1,149 @@
+package squiggle
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+)
+
+// A named value of a compiled query, supplied every time the query is
+// executed.  A slot can be used wherever a value is bound to a placeholder:
+// predicates, Arg() and the rows of an INSERT.  A slot is a single value,
+// In() with a slot renders one placeholder.  Executing a query that isn't
+// compiled with a slot fails, a slot never reaches the database as a value.
+//
+// 	squiggle.Select().AddFrom("users").Where(squiggle.Eq("id", squiggle.Slot("id"))).Compile()
+type Slot string
+
+// Implements driver.Valuer returning an error, so a slot without a value
+// passed to database/sql fails instead of being sent as its name
+func (s Slot) Value() (driver.Value, error) {
+	return nil, s.unfilled()
+}
+
+// returns the error of a slot without a value
+func (s Slot) unfilled() error {
+	return fmt.Errorf("squiggle: the slot %q has no value, compile the query and execute the template", string(s))
+}
+
+// returns the error of the first slot among args
+func unfilledSlot(args []interface{}) error {
+	for _, arg := range args {
+		if slot, ok := arg.(Slot); ok {
+			return slot.unfilled()
+		}
+	}
+	return nil
+}
+
+// the key of the query value telling middleware a query is being compiled
+type compilingKey struct{}
+
+// Reports whether a query is being compiled.  Middleware binding values
+// that change from one execution to the next, such as a tenant, must bind
+// slots then so the template doesn't keep the value it was compiled with.
+func (q *Query) Compiling() bool {
+	return q.Value(compilingKey{}) != nil
+}
+
+// A query compiled into its SQL and the positions of the slots in its
+// arguments, so a query that only differs in its values is rendered once.
+// Templates are safe for concurrent use.
+type Template struct {
+	query *Query
+	// the SQL with and without the comment holding the tags of the query
+	sql  string
+	body string
+	args []interface{}
+	// the positions of the arguments of every slot
+	slots map[string][]int
+}
+
+// Renders the query once into a template.  Middleware is applied when the
+// query is compiled, values middleware binds are part of the template unless
+// they're slots too.  The tenant of a query of a tenant scoped table must be
+// a slot, compiling one with a tenant panics with a *TenantError.
+//
+// 	byID := squiggle.Select().AddFrom("users").Where(squiggle.Eq("id", squiggle.Slot("id"))).Compile()
+// 	byID.Args(map[string]interface{}{"id": 1})
+// 	// => []interface{}{1}
+//
+// 	byTenant := squiggle.Select().AddFrom("users").WithTenant(squiggle.Slot("tenant")).Compile()
+// 	byTenant.Args(map[string]interface{}{"tenant": 42})
+// 	// => []interface{}{42}
+func (q *Query) Compile() *Template {
+	rewritten := q.Mutable().WithValue(compilingKey{}, true).applyMiddleware()
+	rewritten.mustValidate()
+	w := &sqlWriter{}
+	rewritten.toSQL(w)
+
+	t := &Template{query: q.Clone(), body: w.String(), args: w.args, slots: map[string][]int{}}
+	t.sql = t.withComment(q)
+	for i, arg := range t.args {
+		if slot, ok := arg.(Slot); ok {
+			t.slots[string(slot)] = append(t.slots[string(slot)], i)
+		}
+	}
+
+	return t
+}
+
+// Returns the SQL of the template
+func (t *Template) SQL() string {
+	return t.sql
+}
+
+// Returns the names of the slots of the template in alphabetical order
+func (t *Template) Slots() []string {
+	var names []string
+	for name := range t.slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the arguments of the template with the value of every slot taken
+// from values.  Every slot needs a value and every value a slot.
+func (t *Template) Args(values map[string]interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(t.args))
+	copy(args, t.args)
+	for name, positions := range t.slots {
+		value, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("squiggle: no value for the slot %q", name)
+		}
+		for _, i := range positions {
+			args[i] = value
+		}
+	}
+	for name := range values {
+		if _, ok := t.slots[name]; !ok {
+			return nil, fmt.Errorf("squiggle: the template has no slot %q", name)
+		}
+	}
+
+	return args, nil
+}
+
+// returns the SQL of the template with the comment holding the tags of q
+func (t *Template) withComment(q *Query) string {
+	comment := q.comment()
+	if comment == "" {
+		return t.body
+	}
+	if q.commentPosition == CommentStart {
+		return comment + " " + t.body
+	}
+	return t.body + " " + comment
+}
+
+// returns the query of the template tagged with tags it doesn't have yet
+// and its SQL
+func (t *Template) tagged(tags map[string]string) (*Query, string) {
+	q := t.query.withDefaultTags(tags)
+	if q == t.query {
+		return q, t.sql
+	}
+	return q, t.withComment(q)
+}
@@ -0,0 +1,114 @@
+package squiggle
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func Test_Compile(t *testing.T) {
+	q := Select().AddFrom("users").
+		Where(And(Eq("status", "active"), Or(Eq("id", Slot("id")), Eq("parent_id", Slot("id"))), Gt("age", Slot("age")))).
+		SetPlaceholder("$").Tag("app", "x")
+	tmpl := q.Compile()
+
+	expected := "SELECT * FROM users WHERE status = $1 AND (id = $2 OR parent_id = $3) AND age > $4 /*app='x'*/"
+	if tmpl.SQL() != expected {
+		t.Errorf("SQL() returned `%s` expected `%s`", tmpl.SQL(), expected)
+	}
+	if !reflect.DeepEqual(tmpl.Slots(), []string{"age", "id"}) {
+		t.Errorf("Slots() returned %v", tmpl.Slots())
+	}
+
+	args, err := tmpl.Args(map[string]interface{}{"id": 7, "age": 30})
+	if err != nil || !reflect.DeepEqual(args, []interface{}{"active", 7, 7, 30}) {
+		t.Errorf("Args() returned %v, %v", args, err)
+	}
+	if _, err := tmpl.Args(map[string]interface{}{"id": 7}); err == nil {
+		t.Error("Args() should return an error for a slot without a value")
+	}
+	if _, err := tmpl.Args(map[string]interface{}{"id": 7, "age": 30, "name": "bob"}); err == nil {
+		t.Error("Args() should return an error for a value without a slot")
+	}
+
+	q.Tag("route", "/a").Where("a = 1")
+	if tmpl.SQL() != expected {
+		t.Error("changing the query changed the template")
+	}
+	if q.Compiling() {
+		t.Error("Compile() modified the query")
+	}
+}
+
+func Test_CompileTenant(t *testing.T) {
+	Use(TenantScope("tenant_id", "users"))
+	defer ResetMiddleware()
+
+	tmpl := Select().AddFrom("users").Where(Eq("id", Slot("id"))).WithTenant(Slot("tenant")).Compile()
+	args, err := tmpl.Args(map[string]interface{}{"id": 1, "tenant": 42})
+	if err != nil || !reflect.DeepEqual(args, []interface{}{1, 42}) {
+		t.Errorf("Args() returned %v, %v", args, err)
+	}
+
+	defer func() {
+		if e, ok := recover().(*TenantError); !ok || e.Tenant != 42 {
+			t.Errorf("compiling a query with a tenant that isn't a slot should panic with a *TenantError")
+		}
+	}()
+	Select().AddFrom("users").WithTenant(42).Compile()
+}
+
+func Test_RunnerTemplate(t *testing.T) {
+	db, _ := sql.Open("squiggle-fake", "")
+	defer db.Close()
+	fake.queries = nil
+
+	var events []QueryEvent
+	runner := NewRunner(db, HookFuncs{AfterFunc: func(ctx context.Context, e *QueryEvent) {
+		events = append(events, *e)
+	}})
+	deleteUser := Delete("users").Where(Eq("id", Slot("id"))).Compile()
+	byStatus := Select().AddFrom("users").Where(Eq("status", Slot("status"))).Compile()
+
+	ctx := ContextWithTags(context.Background(), map[string]string{"route": "/a"})
+	if result, err := runner.ExecTemplate(ctx, deleteUser, map[string]interface{}{"id": 1}); err != nil {
+		t.Fatalf("ExecTemplate() returned error `%s`", err)
+	} else if n, _ := result.RowsAffected(); n != 1 {
+		t.Errorf("ExecTemplate() returned %d rows affected", n)
+	}
+	rows, err := runner.QueryTemplate(context.Background(), byStatus, map[string]interface{}{"status": "active"})
+	if err != nil {
+		t.Fatalf("QueryTemplate() returned error `%s`", err)
+	}
+	rows.Close()
+	if _, err := runner.QueryTemplate(ctx, byStatus, nil); err == nil {
+		t.Error("QueryTemplate() should return the error of a missing value")
+	}
+
+	if _, err := runner.Exec(ctx, Delete("users").Where(Eq("id", Slot("id")))); err == nil {
+		t.Error("Exec() should return an error for a slot without a value")
+	}
+	if _, err := db.Exec("DELETE FROM users WHERE id = ?", Slot("id")); err == nil {
+		t.Error("a slot passed to database/sql should fail")
+	}
+
+	expected := []string{"DELETE FROM users WHERE id = ? /*route='%2Fa'*/", "SELECT * FROM users WHERE status = ?"}
+	if !reflect.DeepEqual(fake.queries, expected) {
+		t.Errorf("database received %v expected %v", fake.queries, expected)
+	}
+	if len(events) != 4 || !reflect.DeepEqual(events[1].Args, []interface{}{"active"}) || len(events[1].Query.Tags()) != 0 {
+		t.Errorf("unexpected events %+v", events)
+	} else if events[2].Err == nil || events[3].Err == nil {
+		t.Errorf("events of queries that weren't executed have no error %+v", events[2:])
+	}
+}
+
+func BenchmarkTemplateArgs(b *testing.B) {
+	tmpl := Select().AddField("id", "name").AddFrom("users").Where(Eq("id", Slot("id"))).Compile()
+	values := map[string]interface{}{"id": 1}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tmpl.Args(values)
+	}
+}
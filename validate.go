@@ -0,0 +1,340 @@
+package squiggle
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// How serious a problem found by Validate() is
+type Severity int
+
+const (
+	// the query is valid but likely not what was meant
+	SeverityWarning Severity = iota
+	// the database will reject the query or it's ambiguous
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// A Problem found by Validate().  Code identifies the kind of problem, for
+// example "join-without-on".
+type Problem struct {
+	Severity Severity
+	Code     string
+	Message  string
+}
+
+func (p Problem) String() string {
+	return p.Severity.String() + ": " + p.Message
+}
+
+// The problems of a query, rendering a query with errors panics with them
+// when the query validates strictly, see StrictValidation()
+type Problems []Problem
+
+func (p Problems) Error() string {
+	var messages []string
+	for _, problem := range p {
+		messages = append(messages, problem.String())
+	}
+	return "squiggle: " + strings.Join(messages, "; ")
+}
+
+// Returns the problems that are errors
+func (p Problems) Errors() Problems {
+	var errors Problems
+	for _, problem := range p {
+		if problem.Severity == SeverityError {
+			errors = append(errors, problem)
+		}
+	}
+	return errors
+}
+
+// Checks a query for mistakes that render silently but that the database
+// rejects or that likely aren't meant: HAVING without GROUP BY, OFFSET
+// without LIMIT on MySQL, tables and aliases used twice, subqueries in FROM
+// without an alias, joins without ON and fields, groupings, orderings,
+// columns and predicates qualified by a table or alias the query doesn't
+// have.  The columns of tables registered with Columns are checked as well:
+// references to columns the tables don't have, unqualified columns more than
+// one table has and predicates comparing a column to a value of another
+// type.  Middleware is applied first and subqueries in FROM are checked too,
+// errors middleware panics with such as a missing tenant are reported as a
+// "middleware" problem.  Unqualified orderings of queries with GROUP BY or
+// DISTINCT must be a field, an alias or a grouping of the query.  Queries
+// are validated when they're rendered with StrictValidation().
+//
+// 	squiggle.Select().AddFrom("users").AddJoin(squiggle.Join{Type: "left", Table: "orders"}).Validate()
+// 	// => Problems{{Severity: squiggle.SeverityError, Code: "join-without-on", Message: "LEFT JOIN orders has no ON criteria"}}
+func (q *Query) Validate() Problems {
+	q, err := q.tryMiddleware()
+	if err != nil {
+		return Problems{{Severity: SeverityError, Code: "middleware", Message: err.Error()}}
+	}
+	if q.queryType != "SELECT" {
+		return nil
+	}
+
+	var problems Problems
+	add := func(severity Severity, code, format string, args ...interface{}) {
+		problems = append(problems, Problem{Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(q.having.expressions) > 0 && len(q.groupings) == 0 {
+		add(SeverityWarning, "having-without-grouping", "HAVING without GROUP BY treats the whole result as one group")
+	}
+	if q.offset > 0 && q.limit <= 0 && q.dialect.Name == MySQL.Name {
+		add(SeverityError, "offset-without-limit", "MySQL doesn't support OFFSET without LIMIT")
+	}
+
+	// the tables of the query by the name they're referred to by: their
+	// alias or their name
+	var scope []scopeTable
+	addTable := func(table, alias string, subquery bool) {
+		name := alias
+		if name == "" {
+			name = table
+		}
+		if name == "" {
+			return
+		}
+		if _, ok := findTable(scope, name); ok {
+			if alias != "" {
+				add(SeverityError, "duplicate-alias", "the alias %s is used twice", alias)
+			} else {
+				add(SeverityError, "duplicate-alias", "the table %s is used twice without an alias", table)
+			}
+			return
+		}
+		registered, ok := lookupTable(table)
+		known := ok && !subquery && len(registered.Columns) > 0
+		scope = append(scope, scopeTable{name: name, table: registered, known: known})
+	}
+	for _, from := range q.from {
+		if from.Subquery != nil {
+			if from.Alias == "" {
+				add(SeverityError, "subquery-without-alias", "a subquery in FROM has no alias")
+			}
+			for _, problem := range from.Subquery.subqueryOf(q).Validate() {
+				problem.Message = strings.TrimSpace("subquery "+from.Alias) + ": " + problem.Message
+				problems = append(problems, problem)
+			}
+			addTable("", from.Alias, true)
+			continue
+		}
+		addTable(from.Table, from.Alias, false)
+	}
+	for _, join := range q.joins {
+		if len(join.On.expressions) == 0 && joinHasOn(join) {
+			add(SeverityError, "join-without-on", "%s has no ON criteria", strings.TrimSpace(strings.ToUpper(join.Type)+" JOIN "+join.Table))
+		}
+		addTable(join.Table, join.Alias, false)
+	}
+
+	q.validateOrderings(add)
+
+	// references can only be checked when every table of the query is known
+	if len(scope) == 0 {
+		return problems
+	}
+	var aliases []string
+	for _, field := range q.fields {
+		if field.Alias != "" {
+			aliases = append(aliases, field.Alias)
+		}
+	}
+
+	// resolves a reference to a column, returning its type when the column
+	// is found in a table with registered columns and its type is known
+	resolve := func(clause, table, name string) (ValueType, bool) {
+		if table == "" {
+			if i := strings.LastIndexByte(name, '.'); i >= 0 {
+				table = name[:i]
+				table = table[strings.LastIndexByte(table, '.')+1:]
+				name = name[i+1:]
+			}
+		}
+
+		if table != "" {
+			t, ok := findTable(scope, table)
+			if !ok {
+				add(SeverityError, "unknown-table", "%s refers to %s which isn't a table or alias of the query", clause, table)
+				return 0, false
+			}
+			if name == "*" || !t.known {
+				return 0, false
+			}
+			valueType, typed, ok := t.table.column(name)
+			if !ok {
+				add(SeverityError, "unknown-column", "%s refers to the column %s which %s doesn't have", clause, name, t.name)
+			}
+			return valueType, typed
+		}
+
+		if name == "*" || matchesFold(aliases, name) {
+			return 0, false
+		}
+		var found []string
+		var valueType ValueType
+		var typed bool
+		allKnown := true
+		for _, t := range scope {
+			if !t.known {
+				allKnown = false
+			} else if columnType, columnTyped, ok := t.table.column(name); ok {
+				found = append(found, t.name)
+				valueType, typed = columnType, columnTyped
+			}
+		}
+		if len(found) > 1 {
+			add(SeverityError, "ambiguous-column", "%s refers to the column %s which %s all have, qualify it", clause, name, strings.Join(found, ", "))
+		} else if len(found) == 0 && allKnown {
+			add(SeverityError, "unknown-column", "%s refers to the column %s which none of the tables of the query have", clause, name)
+		}
+		return valueType, len(found) == 1 && typed
+	}
+
+	Inspect(q, func(node interface{}) bool {
+		switch n := node.(type) {
+		case *Query:
+			return n == q
+		case From:
+			// subqueries are validated on their own
+			return false
+		case Field:
+			if n.Expr == nil && n.Expression == "" {
+				resolve("field "+n.Name, n.Table, n.Name)
+			}
+		case Grouping:
+			resolve("GROUP BY "+n.Field, n.Table, n.Field)
+		case Ordering:
+			resolve("ORDER BY "+n.Field, n.Table, n.Field)
+		case Column:
+			resolve("column "+n.Name, n.Table, n.Name)
+		case Predicate:
+			valueType, ok := resolve("predicate on "+n.Field, n.Table, n.Field)
+			if ok && !valueHasType(n.Value, valueType) {
+				add(SeverityWarning, "type-mismatch", "predicate on %s compares it to a value of type %T", n.Field, n.Value)
+			}
+		}
+		return true
+	})
+
+	return problems
+}
+
+// reports unqualified orderings of a query with GROUP BY or DISTINCT that
+// aren't a field, an alias or a grouping, which the database rejects.
+// Orderings that aren't plain names, such as COUNT(*), aren't checked.
+func (q *Query) validateOrderings(add func(severity Severity, code, format string, args ...interface{})) {
+	if len(q.groupings) == 0 && !q.distinct {
+		return
+	}
+	var names []string
+	for _, field := range q.fields {
+		if field.Expr == nil && field.Expression == "" && field.Name == "*" {
+			// every column is a field
+			return
+		}
+		names = append(names, field.Alias, field.Name)
+	}
+	if len(q.fields) == 0 {
+		return
+	}
+	for _, grouping := range q.groupings {
+		names = append(names, grouping.Field)
+	}
+
+	for _, ordering := range q.orderings {
+		if ordering.Table != "" || !safeIdentifier.MatchString(ordering.Field) || matchesFold(names, ordering.Field) {
+			continue
+		}
+		add(SeverityError, "unknown-ordering", "ORDER BY %s is neither a field, an alias nor a grouping of the query", ordering.Field)
+	}
+}
+
+// a table of a query and the name it's referred to by.  known is true when
+// the columns of the table are registered.
+type scopeTable struct {
+	name  string
+	table Table
+	known bool
+}
+
+// returns the table of a query referred to by a name
+func findTable(scope []scopeTable, name string) (scopeTable, bool) {
+	for _, t := range scope {
+		if strings.EqualFold(t.name, name) {
+			return t, true
+		}
+	}
+	return scopeTable{}, false
+}
+
+// reports whether a value fits a column of a type.  NULL, slots and values
+// of other types, which the driver converts, fit any column.
+func valueHasType(value interface{}, valueType ValueType) bool {
+	if _, ok := value.(Slot); ok {
+		return true
+	}
+	if isList(value) {
+		for _, item := range listValues(value) {
+			if !valueHasType(item, valueType) {
+				return false
+			}
+		}
+		return true
+	}
+
+	v, err := matchValue(value)
+	if err != nil {
+		return true
+	}
+	switch v.(type) {
+	case int64, uint64:
+		return valueType == IntValue || valueType == FloatValue
+	case float64:
+		return valueType == FloatValue
+	case string:
+		return valueType == StringValue || valueType == TimeValue
+	case []byte:
+		return valueType == StringValue
+	case bool:
+		return valueType == BoolValue
+	case time.Time:
+		return valueType == TimeValue
+	}
+	return true
+}
+
+// Makes rendering the query validate it first, rendering a query with
+// errors panics with its Problems, see Validate().  Warnings don't keep a
+// query from rendering.
+//
+// 	squiggle.Select().AddFrom("users").AddJoin(squiggle.Join{Table: "orders"}).StrictValidation().String()
+// 	// panics with squiggle: error: JOIN orders has no ON criteria
+func (q *Query) StrictValidation() *Query {
+	q = q.builder()
+	q.strictValidation = true
+
+	return q
+}
+
+// panics with the errors of a query that validates strictly.  Middleware
+// must be applied to the query already so rendering doesn't apply it again.
+func (q *Query) mustValidate() {
+	if !q.strictValidation {
+		return
+	}
+	if errors := q.Validate().Errors(); len(errors) > 0 {
+		panic(errors)
+	}
+}
@@ -0,0 +1,115 @@
+package squiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Validate(t *testing.T) {
+	users := From{Table: "users", Alias: "u"}
+	sub := Select().AddFrom("orders").AddJoin(Join{Table: "items"})
+
+	tests := []struct {
+		q        *Query
+		expected []string
+	}{
+		{Select().AddFrom(users).AddJoin(Join{Type: "left", Table: "orders", Alias: "o", On: And("o.user_id = u.id")}).AddOrdering("u.name", Ordering{Table: "o", Field: "total"}), nil},
+		{Select().AddFrom("users").Having("COUNT(*) > 1"), []string{"having-without-grouping"}},
+		{Select().AddFrom("users").Offset(10).SetDialect(MySQL), []string{"offset-without-limit"}},
+		{Select().AddFrom("users").Offset(10).SetDialect(PostgreSQL), nil},
+		{Select().AddFrom(users).AddJoin(Join{Table: "orders", Alias: "U", On: And("a = b")}), []string{"duplicate-alias"}},
+		{Select().AddFrom("users").AddJoin(Join{Table: "users", On: And("a = b")}), []string{"duplicate-alias"}},
+		{Select().AddFrom("users").AddJoin(Join{Type: "left", Table: "orders"}, Join{Type: "cross", Table: "days"}), []string{"join-without-on"}},
+		{Select().AddFrom(users).AddOrdering("users.name").AddGrouping(Grouping{Table: "x", Field: "id"}).AddField("db.o.id"), []string{"unknown-table", "unknown-table", "unknown-table"}},
+		{Select().AddFrom(From{Subquery: sub}), []string{"subquery-without-alias", "join-without-on"}},
+		{Delete("users").Having("a = 1"), nil},
+		{Select().AddField(Field{Expression: "COUNT(*)", Alias: "n"}, "status").AddFrom("users").AddGrouping("status").AddOrdering("n", "status", "cnt", Ordering{Field: "COUNT(*)"}), []string{"unknown-ordering"}},
+		{Select().Distinct().AddField("name").AddFrom("users").AddOrdering("name", "id"), []string{"unknown-ordering"}},
+		{Select().AddField("name").AddFrom("users").AddOrdering("id"), nil},
+		{Select().Distinct().AddFrom("users").AddOrdering("id"), nil},
+	}
+	for _, test := range tests {
+		var codes []string
+		for _, problem := range test.q.Validate() {
+			codes = append(codes, problem.Code)
+		}
+		if !reflect.DeepEqual(codes, test.expected) {
+			t.Errorf("Validate() of `%s` returned %v expected %v", test.q, test.q.Validate(), test.expected)
+		}
+	}
+
+	problems := Select().AddFrom("users").AddJoin(Join{Type: "left", Table: "orders"}).Validate()
+	expected := "squiggle: error: LEFT JOIN orders has no ON criteria"
+	if problems.Error() != expected {
+		t.Errorf("Error() returned `%s` expected `%s`", problems.Error(), expected)
+	}
+}
+
+func Test_ValidateStrict(t *testing.T) {
+	q := Select().AddFrom("users").Having("COUNT(*) > 1").StrictValidation()
+	if str := q.String(); str != "SELECT * FROM users HAVING COUNT(*) > 1" {
+		t.Errorf("String() returned `%s` for a query with warnings", str)
+	}
+	if str := Select().AddFrom("users").AddJoin(Join{Table: "orders"}).StrictIdentifiers().String(); str != "SELECT * FROM users JOIN orders" {
+		t.Errorf("String() returned `%s`, StrictIdentifiers() should not validate", str)
+	}
+
+	applied := 0
+	counter := Middleware{Name: "counter", Rewrite: func(q *Query) *Query {
+		applied++
+		return q
+	}}
+	_ = q.Clone().Use(counter).String()
+	if applied != 1 {
+		t.Errorf("middleware was applied %d times rendering a query that validates strictly", applied)
+	}
+
+	defer func() {
+		problems, ok := recover().(Problems)
+		if !ok || len(problems) != 1 || problems[0].Code != "join-without-on" {
+			t.Errorf("ToSQL() panicked with %v", problems)
+		}
+	}()
+	q.AddJoin(Join{Table: "orders"}).ToSQL()
+	t.Error("ToSQL() should panic for a query with errors in strict mode")
+}
+
+func Test_ValidateColumns(t *testing.T) {
+	RegisterTable(
+		Table{Name: "users", Alias: "u", SoftDelete: "deleted_at", Columns: map[string]ValueType{"id": IntValue, "name": StringValue, "status": StringValue}},
+		Table{Name: "orders", Columns: map[string]ValueType{"id": IntValue, "user_id": IntValue, "total": FloatValue}},
+	)
+	defer ResetTables()
+
+	tests := []struct {
+		q        *Query
+		expected []string
+	}{
+		{Select().AddField("u.name", Field{Table: "orders", Name: "total", Alias: "t"}).AddFrom("users").
+			AddJoin(Join{Table: "orders", On: And(Op(Col("orders.user_id"), "=", Col("u.id")))}).
+			Where(And(Eq("status", "active"), In("u.id", []int{1, 2}), Gt("total", 10))).AddOrdering("t", "name"), nil},
+		{Select().AddField("u.email").AddFrom("users"), []string{"unknown-column"}},
+		{Select().AddFrom("users").Where(Eq("email", "a")), []string{"unknown-column"}},
+		{Select().AddFrom("users").AddJoin(Join{Table: "orders", On: And("a = b")}).AddOrdering("id"), []string{"ambiguous-column"}},
+		{Select().AddFrom("users").AddJoin(Join{Table: "items", On: And("a = b")}).Where(Eq("sku", "a")), nil},
+		{Select().AddFrom("users").Where(And(Eq("id", "bob"), Eq("name", Slot("name")))), []string{"type-mismatch"}},
+		{Select().AddFrom("users").Where(Eq("x.id", 1)), []string{"unknown-table"}},
+		{Select().AddField(Field{Expression: "COUNT(*)", Alias: "n"}).AddFrom("users").AddOrdering("n", "cnt"), []string{"unknown-column"}},
+		{Select().AddFrom("users").Where(And(Eq("deleted_at", true), Eq("u.deleted_at", "2020-01-01"))).WithDeleted(), nil},
+	}
+	for _, test := range tests {
+		var codes []string
+		for _, problem := range test.q.Validate() {
+			codes = append(codes, problem.Code)
+		}
+		if !reflect.DeepEqual(codes, test.expected) {
+			t.Errorf("Validate() of `%s` returned %v expected %v", test.q, test.q.Validate(), test.expected)
+		}
+	}
+
+	problems := Select().AddFrom("users").AddJoin(Join{Table: "orders", On: And("a = b")}).AddGrouping("id").Validate()
+	expected := "squiggle: error: GROUP BY id refers to the column id which u, orders all have, qualify it"
+	if problems.Error() != expected {
+		t.Errorf("Error() returned `%s` expected `%s`", problems.Error(), expected)
+	}
+}
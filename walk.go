@@ -0,0 +1,254 @@
+package squiggle
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Visitor's Visit method is called for every node of a query by Walk().
+// If the visitor returned is not nil Walk() visits the children of the node
+// with it and then calls Visit(nil).
+type Visitor interface {
+	Visit(node interface{}) (w Visitor)
+}
+
+// Walks a query or any part of one in depth-first order.  Nodes are *Query,
+// From, Join, Field, Grouping, Ordering, Criteria, strings of criteria and
+// the expressions of expression trees.  The children of a query are visited
+// in the order of its clauses and subqueries of From are walked like any
+// other query.
+func Walk(node interface{}, v Visitor) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Query:
+		for _, field := range n.fields {
+			Walk(field, v)
+		}
+		for _, from := range n.from {
+			Walk(from, v)
+		}
+		for _, join := range n.joins {
+			Walk(join, v)
+		}
+		if len(n.where.expressions) > 0 {
+			Walk(n.where, v)
+		}
+		for _, grouping := range n.groupings {
+			Walk(grouping, v)
+		}
+		if len(n.having.expressions) > 0 {
+			Walk(n.having, v)
+		}
+		for _, ordering := range n.orderings {
+			Walk(ordering, v)
+		}
+	case Field:
+		if n.Expr != nil {
+			Walk(n.Expr, v)
+		}
+	case From:
+		if n.Subquery != nil {
+			Walk(n.Subquery, v)
+		}
+	case Join:
+		if len(n.On.expressions) > 0 {
+			Walk(n.On, v)
+		}
+	case Criteria:
+		for _, expression := range n.expressions {
+			Walk(expression, v)
+		}
+	case Func:
+		for _, arg := range n.Args {
+			Walk(arg, v)
+		}
+	case Binary:
+		Walk(n.Left, v)
+		Walk(n.Right, v)
+	case Unary:
+		Walk(n.Operand, v)
+	case Case:
+		if n.Operand != nil {
+			Walk(n.Operand, v)
+		}
+		for _, when := range n.Whens {
+			Walk(when.Cond, v)
+			Walk(when.Result, v)
+		}
+		if n.Else != nil {
+			Walk(n.Else, v)
+		}
+	case Cast:
+		Walk(n.Expr, v)
+	case List:
+		for _, e := range n {
+			Walk(e, v)
+		}
+	case Fragment:
+		for _, e := range n {
+			Walk(e, v)
+		}
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(interface{}) bool
+
+func (f inspector) Visit(node interface{}) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Walks a query like Walk() calling f for every node.  The children of a
+// node are only visited when f returns true.
+//
+// 	// list every table a query references
+// 	squiggle.Inspect(q, func(node interface{}) bool {
+// 		switch n := node.(type) {
+// 		case squiggle.From:
+// 			tables = append(tables, n.Table)
+// 		case squiggle.Join:
+// 			tables = append(tables, n.Table)
+// 		}
+// 		return true
+// 	})
+func Inspect(node interface{}, f func(node interface{}) bool) {
+	Walk(node, inspector(func(node interface{}) bool {
+		return node == nil || f(node)
+	}))
+}
+
+// Rewrites a query or any part of one.  The children of every node are
+// rewritten first, then f is called with the node and the node is replaced
+// by what f returns.  f must return a node that fits where the node was,
+// for example a Field for a Field or an Expression for an Expression.
+// Criteria may contain strings, Criteria and Expressions.  Queries are
+// cloned before they are rewritten so the original is never modified.
+//
+// 	// qualify every unqualified column with the table u
+// 	squiggle.Rewrite(q, func(node interface{}) interface{} {
+// 		if c, ok := node.(squiggle.Column); ok && c.Table == "" {
+// 			c.Table = "u"
+// 			return c
+// 		}
+// 		return node
+// 	})
+func Rewrite(node interface{}, f func(node interface{}) interface{}) interface{} {
+	switch n := node.(type) {
+	case *Query:
+		n = n.Clone()
+		for i, field := range n.fields {
+			n.fields[i] = rewriteAs(field, f).(Field)
+		}
+		for i, from := range n.from {
+			n.from[i] = rewriteAs(from, f).(From)
+		}
+		for i, join := range n.joins {
+			n.joins[i] = rewriteAs(join, f).(Join)
+		}
+		if len(n.where.expressions) > 0 {
+			n.where = rewriteAs(n.where, f).(Criteria)
+		}
+		for i, grouping := range n.groupings {
+			n.groupings[i] = rewriteAs(grouping, f).(Grouping)
+		}
+		if len(n.having.expressions) > 0 {
+			n.having = rewriteAs(n.having, f).(Criteria)
+		}
+		for i, ordering := range n.orderings {
+			n.orderings[i] = rewriteAs(ordering, f).(Ordering)
+		}
+		node = n
+	case Field:
+		if n.Expr != nil {
+			n.Expr = rewriteExpression(n.Expr, f)
+		}
+		node = n
+	case From:
+		if n.Subquery != nil {
+			n.Subquery = Rewrite(n.Subquery, f).(*Query)
+		}
+		node = n
+	case Join:
+		if len(n.On.expressions) > 0 {
+			n.On = rewriteAs(n.On, f).(Criteria)
+		}
+		node = n
+	case Criteria:
+		expressions := make([]interface{}, len(n.expressions))
+		for i, expression := range n.expressions {
+			expressions[i] = Rewrite(expression, f)
+		}
+		n.expressions = expressions
+		node = n
+	case Func:
+		args := make([]Expression, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = rewriteExpression(arg, f)
+		}
+		n.Args = args
+		node = n
+	case Binary:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Right = rewriteExpression(n.Right, f)
+		node = n
+	case Unary:
+		n.Operand = rewriteExpression(n.Operand, f)
+		node = n
+	case Case:
+		if n.Operand != nil {
+			n.Operand = rewriteExpression(n.Operand, f)
+		}
+		whens := make([]When, len(n.Whens))
+		for i, when := range n.Whens {
+			whens[i] = When{Cond: rewriteExpression(when.Cond, f), Result: rewriteExpression(when.Result, f)}
+		}
+		n.Whens = whens
+		if n.Else != nil {
+			n.Else = rewriteExpression(n.Else, f)
+		}
+		node = n
+	case Cast:
+		n.Expr = rewriteExpression(n.Expr, f)
+		node = n
+	case List:
+		list := make(List, len(n))
+		for i, e := range n {
+			list[i] = rewriteExpression(e, f)
+		}
+		node = list
+	case Fragment:
+		fragment := make(Fragment, len(n))
+		for i, e := range n {
+			fragment[i] = rewriteExpression(e, f)
+		}
+		node = fragment
+	}
+
+	return f(node)
+}
+
+// rewrites a node that must stay the same type
+func rewriteAs(node interface{}, f func(node interface{}) interface{}) interface{} {
+	rewritten := Rewrite(node, f)
+	if reflect.TypeOf(rewritten) != reflect.TypeOf(node) {
+		panic(fmt.Sprintf("unexpected type %T returned by Rewrite() for %T", rewritten, node))
+	}
+	return rewritten
+}
+
+// rewrites a node that must stay an expression
+func rewriteExpression(e Expression, f func(node interface{}) interface{}) Expression {
+	rewritten := Rewrite(e, f)
+	expression, ok := rewritten.(Expression)
+	if !ok {
+		panic(fmt.Sprintf("unexpected type %T returned by Rewrite() for %T", rewritten, e))
+	}
+	return expression
+}
@@ -0,0 +1,92 @@
+package squiggle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Inspect(t *testing.T) {
+	sub := Select().AddFrom(From{Schema: "archive", Table: "orders"}).Where(Eq("status", "done"))
+	q1 := Select().
+		AddField("id", Field{Expr: Fn("LOWER", Col("u.name"))}).
+		AddFrom(From{Table: "users", Alias: "u"}, From{Subquery: sub, Alias: "o"}).
+		AddJoin(Join{Type: "inner", Schema: "auth", Table: "roles", On: And("roles.id = u.role_id")}).
+		Where(Or(Eq("u.active", true), Op(Col("u.age"), ">", Arg(10))))
+
+	var tables, columns []string
+	Inspect(q1, func(node interface{}) bool {
+		switch n := node.(type) {
+		case From:
+			if n.Subquery == nil {
+				tables = append(tables, n.Table)
+			}
+		case Join:
+			tables = append(tables, n.Table)
+		case Predicate:
+			columns = append(columns, n.Field)
+		case Column:
+			columns = append(columns, n.Table+"."+n.Name)
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(tables, []string{"users", "orders", "roles"}) {
+		t.Errorf("Inspect() found tables %v", tables)
+	}
+	if !reflect.DeepEqual(columns, []string{"u.name", "status", "u.active", "u.age"}) {
+		t.Errorf("Inspect() found columns %v", columns)
+	}
+
+	count := 0
+	Inspect(q1, func(node interface{}) bool {
+		count++
+		_, isQuery := node.(*Query)
+		return isQuery
+	})
+	if count != 7 {
+		t.Errorf("Inspect() visited %d nodes without descending expected 7", count)
+	}
+}
+
+func Test_Rewrite(t *testing.T) {
+	sub := Select().AddFrom("orders").Where(Eq("status", "done"))
+	q1 := Select().
+		AddField(Field{Expr: Col("name")}).
+		AddFrom(From{Subquery: sub, Alias: "o"}).
+		Where(And("a = 1", Op(Col("age"), ">", Arg(10)), Fragment{Col("score"), Raw(" > 5")}))
+	original := q1.String()
+
+	q2 := Rewrite(q1, func(node interface{}) interface{} {
+		switch n := node.(type) {
+		case Column:
+			n.Table = "u"
+			return n
+		case Predicate:
+			n.Table = "o"
+			return n
+		case string:
+			return "b = 2"
+		}
+		return node
+	}).(*Query)
+
+	expected := "SELECT u.name FROM (SELECT * FROM orders WHERE o.status = ?) o WHERE b = 2 AND u.age > ? AND u.score > 5"
+	if str := q2.String(); str != expected {
+		t.Errorf("Rewrite() returned `%s` expected `%s`", str, expected)
+	}
+	if str := q1.String(); str != original {
+		t.Errorf("Rewrite() modified the original query `%s`", str)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Rewrite() should panic when a node is replaced by the wrong type")
+		}
+	}()
+	Rewrite(q1, func(node interface{}) interface{} {
+		if _, ok := node.(Field); ok {
+			return "name"
+		}
+		return node
+	})
+}